@@ -1,16 +1,71 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	Base struct {
+		Project  string
+		Env      string
+		TimeZone string
+	}
+)
 
 var (
+	mu           sync.RWMutex
 	env          string
 	timeLocation time.Location
 )
 
 func Env() string {
+	mu.RLock()
+	defer mu.RUnlock()
 	return env
 }
 
 func TimeLocation() time.Location {
+	mu.RLock()
+	defer mu.RUnlock()
 	return timeLocation
 }
+
+func (b *Base) base() *Base {
+	return b
+}
+
+func (b *Base) validate() error {
+	switch b.Env {
+	case Dev, Test, Pre, Prd:
+	default:
+		return fmt.Errorf("%w: %q, expect one of %s/%s/%s/%s", ErrInvalidEnv, b.Env, Dev, Test, Pre, Prd)
+	}
+
+	if _, err := b.location(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTimeZone, err.Error())
+	}
+
+	return nil
+}
+
+func (b *Base) location() (*time.Location, error) {
+	if len(b.TimeZone) == 0 {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(b.TimeZone)
+}
+
+func (b *Base) apply() {
+	loc, err := b.location()
+	if err != nil {
+		return
+	}
+
+	mu.Lock()
+	env = b.Env
+	timeLocation = *loc
+	mu.Unlock()
+}
@@ -10,5 +10,7 @@ const (
 const (
 	Project    = "era"
 	TraceID    = "trace-id"
+	SpanID     = "span-id"
+	Sampled    = "sampled"
 	TimeFormat = "2006-01-02 15:04:05"
 )
@@ -0,0 +1,413 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type leaf struct {
+	keys  []string
+	names []string
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (lf leaf) String() string {
+	return strings.Join(lf.keys, ".")
+}
+
+func (lf leaf) envName() string {
+	parts := make([]string, 0, len(lf.names))
+	for _, n := range lf.names {
+		parts = append(parts, strings.ToUpper(snake(n)))
+	}
+
+	return strings.Join(parts, "_")
+}
+
+func (lf leaf) flagName() string {
+	parts := make([]string, 0, len(lf.names))
+	for _, n := range lf.names {
+		parts = append(parts, strings.ReplaceAll(snake(n), "_", "-"))
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func normalizeKey(k string) string {
+	k = strings.ToLower(k)
+	k = strings.ReplaceAll(k, "_", "")
+	return strings.ReplaceAll(k, "-", "")
+}
+
+func snake(s string) string {
+	var sb strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+				sb.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+func fieldKeys(f reflect.StructField) []string {
+	keys := []string{normalizeKey(f.Name)}
+	for _, tag := range []string{"yaml", "json", "toml"} {
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if len(name) > 0 && name != "-" {
+			keys = append(keys, normalizeKey(name))
+		}
+	}
+
+	return keys
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// leafPaths lists every scalar field reachable from t, the ones env and flags can address.
+func leafPaths(t reflect.Type, parent *leaf, seen []reflect.Type) []leaf {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for _, s := range seen {
+		if s == t {
+			return nil
+		}
+	}
+	seen = append(seen, t)
+
+	if parent == nil {
+		parent = &leaf{}
+	}
+
+	var leaves []leaf
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 && !f.Anonymous {
+			continue
+		}
+
+		if f.Anonymous {
+			leaves = append(leaves, leafPaths(f.Type, parent, seen)...)
+			continue
+		}
+
+		lf := leaf{
+			keys:  append(append([]string{}, parent.keys...), normalizeKey(f.Name)),
+			names: append(append([]string{}, parent.names...), f.Name),
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		switch {
+		case isScalar(ft):
+			leaves = append(leaves, lf)
+		case ft.Kind() == reflect.Slice && isScalar(ft.Elem()):
+			leaves = append(leaves, lf)
+		case ft.Kind() == reflect.Struct:
+			leaves = append(leaves, leafPaths(ft, &lf, seen)...)
+		}
+	}
+
+	return leaves
+}
+
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, vv := range val {
+			m[normalizeKey(k)] = normalize(vv)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, vv := range val {
+			m[normalizeKey(fmt.Sprint(k))] = normalize(vv)
+		}
+		return m
+	case nil, string, []byte:
+		return val
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			s[i] = normalize(rv.Index(i).Interface())
+		}
+		return s
+	}
+
+	return v
+}
+
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		sm, ok1 := v.(map[string]interface{})
+		dm, ok2 := dst[k].(map[string]interface{})
+		if ok1 && ok2 {
+			merge(dm, sm)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
+
+func setPath(tree map[string]interface{}, keys []string, v interface{}) {
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := tree[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			tree[k] = next
+		}
+		tree = next
+	}
+
+	tree[keys[len(keys)-1]] = v
+}
+
+func decode(v reflect.Value, data interface{}, path string) error {
+	if data == nil {
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decode(v.Elem(), data, path)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("config %s: expect a table, got %T", path, data)
+		}
+		return decodeStruct(v, m, path)
+	case reflect.Map:
+		m, ok := data.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("config %s: expect a table, got %T", path, data)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for k, vv := range m {
+			ev := reflect.New(v.Type().Elem()).Elem()
+			if err := decode(ev, vv, path+"."+k); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), ev)
+		}
+		return nil
+	case reflect.Slice:
+		var items []interface{}
+		switch d := data.(type) {
+		case []interface{}:
+			items = d
+		case string:
+			for _, s := range strings.Split(d, ",") {
+				if s = strings.TrimSpace(s); len(s) > 0 {
+					items = append(items, s)
+				}
+			}
+		default:
+			items = []interface{}{d}
+		}
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decode(s.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(data))
+			return nil
+		}
+	}
+
+	if err := decodeScalar(v, data); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func decodeStruct(v reflect.Value, m map[string]interface{}, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Ptr && fv.IsNil() && fv.CanSet() {
+				fv.Set(reflect.New(f.Type.Elem()))
+			}
+			for fv.Kind() == reflect.Ptr {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.CanSet() {
+				if err := decodeStruct(fv, m, path); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+
+		for _, k := range fieldKeys(f) {
+			data, ok := m[k]
+			if !ok {
+				continue
+			}
+
+			sub := k
+			if len(path) > 0 {
+				sub = path + "." + k
+			}
+			if err := decode(v.Field(i), data, sub); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func decodeScalar(v reflect.Value, data interface{}) error {
+	if v.Type() == durationType {
+		switch d := data.(type) {
+		case string:
+			dur, err := time.ParseDuration(d)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(dur))
+			return nil
+		}
+	}
+
+	s, isString := data.(string)
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fmt.Sprint(data))
+	case reflect.Bool:
+		if isString {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+			return nil
+		}
+		b, ok := data.(bool)
+		if !ok {
+			return fmt.Errorf("expect bool, got %T", data)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isString {
+			n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetInt(n)
+			return nil
+		}
+		f, err := toFloat(data)
+		if err != nil {
+			return err
+		}
+		if f != float64(int64(f)) || v.OverflowInt(int64(f)) {
+			return fmt.Errorf("%v overflows %s", data, v.Type())
+		}
+		v.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isString {
+			n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetUint(n)
+			return nil
+		}
+		f, err := toFloat(data)
+		if err != nil {
+			return err
+		}
+		if f < 0 || f != float64(uint64(f)) || v.OverflowUint(uint64(f)) {
+			return fmt.Errorf("%v overflows %s", data, v.Type())
+		}
+		v.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		if isString {
+			f, err := strconv.ParseFloat(s, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetFloat(f)
+			return nil
+		}
+		f, err := toFloat(data)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func toFloat(data interface{}) (float64, error) {
+	switch n := data.(type) {
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+
+	return 0, fmt.Errorf("expect number, got %T", data)
+}
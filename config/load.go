@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+type (
+	// Loader merges config files, environment variables and command-line flags,
+	// in that order of precedence, into a typed struct.
+	Loader struct {
+		files     []string
+		envPrefix string
+		flagSet   *flag.FlagSet
+		args      []string
+		flags     map[string]string
+	}
+
+	Validator interface {
+		Validate() error
+	}
+
+	Option func(*Loader)
+
+	baser interface {
+		base() *Base
+	}
+)
+
+var (
+	ErrInvalidTarget   = errors.New("config target must be a non-nil pointer to struct")
+	ErrUnsupportedFile = errors.New("unsupported config file")
+	ErrInvalidEnv      = errors.New("invalid env")
+	ErrInvalidTimeZone = errors.New("invalid time zone")
+	ErrFlagsParsed     = errors.New("config flags already parsed")
+)
+
+func NewLoader(opts ...Option) *Loader {
+	l := &Loader{}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Load fills out with a one-shot loader built from opts.
+func Load(out interface{}, opts ...Option) error {
+	return NewLoader(opts...).Load(out)
+}
+
+// WithFile adds a yaml/toml/json file, later files override earlier ones.
+func WithFile(path string) Option {
+	return func(l *Loader) {
+		l.files = append(l.files, path)
+	}
+}
+
+// WithEnvPrefix reads PREFIX_SECTION_FIELD variables, e.g. ERA_REDIS_POOL_SIZE.
+func WithEnvPrefix(prefix string) Option {
+	return func(l *Loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithFlags registers -section.field flags on fs and parses args on the first load,
+// which fails with ErrFlagsParsed when fs was parsed before, e.g. flag.CommandLine after flag.Parse().
+func WithFlags(fs *flag.FlagSet, args []string) Option {
+	return func(l *Loader) {
+		l.flagSet = fs
+		l.args = args
+	}
+}
+
+func (l *Loader) Files() []string {
+	return l.files
+}
+
+func (l *Loader) Load(out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+
+	tree := make(map[string]interface{})
+	for _, file := range l.files {
+		data, err := readFile(file)
+		if err != nil {
+			return err
+		}
+		merge(tree, data)
+	}
+
+	leaves := leafPaths(rv.Elem().Type(), nil, nil)
+	merge(tree, l.readEnv(leaves))
+
+	flags, err := l.readFlags(leaves)
+	if err != nil {
+		return err
+	}
+	merge(tree, flags)
+
+	if err := decode(rv.Elem(), tree, ""); err != nil {
+		return err
+	}
+
+	if b, ok := out.(baser); ok {
+		if err := b.base().validate(); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := out.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("config validate: %w", err)
+		}
+	}
+
+	if b, ok := out.(baser); ok {
+		b.base().apply()
+	}
+
+	return nil
+}
+
+func (l *Loader) readEnv(leaves []leaf) map[string]interface{} {
+	tree := make(map[string]interface{})
+	for _, lf := range leaves {
+		name := lf.envName()
+		if len(l.envPrefix) > 0 {
+			name = strings.ToUpper(l.envPrefix) + "_" + name
+		}
+
+		if v, ok := os.LookupEnv(name); ok {
+			setPath(tree, lf.keys, v)
+		}
+	}
+
+	return tree
+}
+
+func (l *Loader) readFlags(leaves []leaf) (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	if l.flagSet == nil {
+		return tree, nil
+	}
+
+	if l.flags == nil {
+		if l.flagSet.Parsed() {
+			return nil, ErrFlagsParsed
+		}
+
+		values := make(map[string]*string, len(leaves))
+		for _, lf := range leaves {
+			name := lf.flagName()
+			if l.flagSet.Lookup(name) == nil {
+				values[name] = l.flagSet.String(name, "", "config "+lf.String())
+			}
+		}
+
+		if err := l.flagSet.Parse(l.args); err != nil {
+			return nil, fmt.Errorf("config flags: %w", err)
+		}
+
+		l.flags = make(map[string]string)
+		l.flagSet.Visit(func(f *flag.Flag) {
+			if _, ok := values[f.Name]; ok {
+				l.flags[f.Name] = f.Value.String()
+			}
+		})
+	}
+
+	for _, lf := range leaves {
+		if v, ok := l.flags[lf.flagName()]; ok {
+			setPath(tree, lf.keys, v)
+		}
+	}
+
+	return tree, nil
+}
+
+func readFile(path string) (map[string]interface{}, error) {
+	var unmarshal func([]byte, interface{}) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	case ".json":
+		unmarshal = json.Unmarshal
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFile, path)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("config read %s: %w", path, err)
+	}
+
+	raw := make(map[string]interface{})
+	if err = unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("config parse %s: %w", path, err)
+	}
+
+	tree, _ := normalize(raw).(map[string]interface{})
+	return tree, nil
+}
@@ -0,0 +1,146 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jaegercfg "github.com/uber/jaeger-client-go/config"
+
+	"github.com/GaVender/era/config"
+	"github.com/GaVender/era/pkg/mongodb"
+	"github.com/GaVender/era/pkg/mysql"
+	"github.com/GaVender/era/pkg/prometheus"
+	"github.com/GaVender/era/pkg/redis"
+)
+
+type appConfig struct {
+	config.Base
+	Redis      redis.Config
+	Mysql      mysql.Config
+	Mongodb    mongodb.Config
+	Prometheus prometheus.Config
+	Jaeger     jaegercfg.Configuration
+}
+
+func TestLoad(t *testing.T) {
+	yamlFile := filepath.Join("testdata", "era.yaml")
+	tomlFile := filepath.Join("testdata", "era.toml")
+	jsonFile := filepath.Join("testdata", "era.json")
+
+	tests := []struct {
+		name    string
+		opts    []config.Option
+		env     map[string]string
+		args    []string
+		check   func(t *testing.T, c appConfig)
+		wantErr error
+	}{
+		{
+			name: "yaml",
+			opts: []config.Option{config.WithFile(yamlFile)},
+			check: func(t *testing.T, c appConfig) {
+				if c.Env != config.Dev || c.Redis.Addr != "127.0.0.1:6379" || c.Redis.PoolSize != 10 {
+					t.Errorf("unexpected base/redis: %+v %+v", c.Base, c.Redis)
+				}
+				if c.Mysql.DBName != "era" || c.Mysql.MaxOpenConn != 20 {
+					t.Errorf("unexpected mysql: %+v", c.Mysql)
+				}
+				if len(c.Mongodb.Hosts) != 2 || c.Mongodb.MaxPoolSize != 50 {
+					t.Errorf("unexpected mongodb: %+v", c.Mongodb)
+				}
+				if c.Prometheus.Host != ":9191" {
+					t.Errorf("unexpected prometheus: %+v", c.Prometheus)
+				}
+				if c.Jaeger.Sampler == nil || c.Jaeger.Sampler.Type != "const" ||
+					c.Jaeger.Reporter == nil || c.Jaeger.Reporter.BufferFlushInterval != time.Second {
+					t.Errorf("unexpected jaeger: %+v", c.Jaeger)
+				}
+				if config.Env() != config.Dev {
+					t.Errorf("config.Env() = %s", config.Env())
+				}
+			},
+		},
+		{
+			name: "later files override",
+			opts: []config.Option{config.WithFile(yamlFile), config.WithFile(tomlFile), config.WithFile(jsonFile)},
+			check: func(t *testing.T, c appConfig) {
+				if c.Env != config.Pre || c.Redis.Addr != "127.0.0.1:6380" || c.Redis.PoolSize != 20 || c.Redis.DB != 1 {
+					t.Errorf("unexpected merge: %+v %+v", c.Base, c.Redis)
+				}
+				if c.Mysql.MaxIdleConn != 5 || c.Mysql.MaxOpenConn != 20 {
+					t.Errorf("unexpected mysql: %+v", c.Mysql)
+				}
+			},
+		},
+		{
+			name: "env over file, flags over env",
+			opts: []config.Option{config.WithFile(yamlFile), config.WithEnvPrefix("era_test")},
+			env: map[string]string{
+				"ERA_TEST_REDIS_POOL_SIZE": "30",
+				"ERA_TEST_MYSQL_DB_NAME":   "env",
+				"ERA_TEST_MONGODB_HOSTS":   "a:1,b:2,c:3",
+			},
+			args: []string{"-mysql.db-name=flag", "-env=prd"},
+			check: func(t *testing.T, c appConfig) {
+				if c.Redis.PoolSize != 30 || c.Mysql.DBName != "flag" || c.Env != config.Prd || len(c.Mongodb.Hosts) != 3 {
+					t.Errorf("unexpected precedence: %+v %+v %+v", c.Base, c.Redis, c.Mysql)
+				}
+			},
+		},
+		{
+			name:    "invalid env",
+			opts:    []config.Option{config.WithFile(yamlFile)},
+			args:    []string{"-env=local"},
+			wantErr: config.ErrInvalidEnv,
+		},
+		{
+			name:    "unsupported file",
+			opts:    []config.Option{config.WithFile("era.ini")},
+			wantErr: config.ErrUnsupportedFile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				if err := os.Setenv(k, v); err != nil {
+					t.Fatal(err)
+				}
+				defer os.Unsetenv(k)
+			}
+
+			opts := append(tt.opts, config.WithFlags(flag.NewFlagSet(tt.name, flag.ContinueOnError), tt.args))
+
+			var c appConfig
+			err := config.Load(&c, opts...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestLoadParsedFlags(t *testing.T) {
+	fs := flag.NewFlagSet("parsed", flag.ContinueOnError)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var c appConfig
+	err := config.Load(&c, config.WithFile(filepath.Join("testdata", "era.yaml")), config.WithFlags(fs, []string{"-env=prd"}))
+	if !errors.Is(err, config.ErrFlagsParsed) {
+		t.Errorf("Load() error = %v, want %v", err, config.ErrFlagsParsed)
+	}
+}
@@ -0,0 +1,198 @@
+package config_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GaVender/era/config"
+	"github.com/GaVender/era/pkg/redis"
+)
+
+type watchConfig struct {
+	config.Base
+	Log struct {
+		Level string
+	}
+	Redis redis.Config
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "era.yaml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("env: dev\nlog:\n  level: info\nredis:\n  pool_size: 10\n")
+
+	tests := []struct {
+		name string
+		opts []config.WatchOption
+	}{
+		{name: "polling", opts: []config.WatchOption{config.WithPolling(10 * time.Millisecond)}},
+		{name: "notify"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write("env: dev\nlog:\n  level: info\nredis:\n  pool_size: 10\n")
+
+			w, err := config.NewWatcher(config.NewLoader(config.WithFile(file)), func() interface{} {
+				return &watchConfig{}
+			}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			events := make(chan config.Event, 10)
+			w.Subscribe("redis.pool_size", func(e config.Event) {
+				events <- e
+			})
+			w.Subscribe("log", func(e config.Event) {
+				t.Errorf("unexpected log event: %+v", e.Changes)
+			})
+
+			time.Sleep(50 * time.Millisecond)
+			write("env: local\nlog:\n  level: info\nredis:\n  pool_size: 20\n")
+			select {
+			case e := <-events:
+				t.Fatalf("invalid snapshot published: %+v", e.Changes)
+			case <-time.After(300 * time.Millisecond):
+			}
+			if c := w.Current().(*watchConfig); c.Redis.PoolSize != 10 {
+				t.Fatalf("invalid snapshot swapped in: %+v", c.Redis)
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			write("env: dev\nlog:\n  level: info\nredis:\n  pool_size: 30\n")
+			select {
+			case e := <-events:
+				if len(e.Changes) != 1 || e.Changes[0].Key != "redis.pool_size" || e.Changes[0].New != 30 {
+					t.Fatalf("unexpected changes: %+v", e.Changes)
+				}
+				if e.New.(*watchConfig).Redis.PoolSize != 30 || e.Old.(*watchConfig).Redis.PoolSize != 10 {
+					t.Fatalf("unexpected snapshots: %+v", e)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("no reload event")
+			}
+		})
+	}
+}
+
+type recordLogger struct {
+	lines []string
+}
+
+func (l *recordLogger) Infof(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *recordLogger) Errorf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestWatcherMasksSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "era.yaml")
+	write := func(conn, password string) {
+		content := "env: dev\nmysql:\n  conn: " + conn + "\nredis:\n  password: " + password + "\n"
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("root:old-secret@tcp(127.0.0.1:3306)/era", "old-redis")
+
+	logger := &recordLogger{}
+	w, err := config.NewWatcher(config.NewLoader(config.WithFile(file)), func() interface{} {
+		return &struct {
+			config.Base
+			Mysql struct {
+				Conn string
+			}
+			Redis redis.Config
+		}{}
+	}, config.WithPolling(time.Hour), config.WithWatchLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	write("root:new@secret@tcp(127.0.0.1:3306)/era", "new-redis")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	logged := strings.Join(logger.lines, "\n")
+	for _, secret := range []string{"old-secret", "new@secret", "secret@tcp", "old-redis", "new-redis"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("%q logged: %s", secret, logged)
+		}
+	}
+	if !strings.Contains(logged, "root:******@tcp(127.0.0.1:3306)/era") {
+		t.Errorf("dsn not logged: %s", logged)
+	}
+}
+
+func TestWatcherConcurrentReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "era.yaml")
+	if err := ioutil.WriteFile(file, []byte("env: dev\nredis:\n  pool_size: 10\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := config.NewWatcher(config.NewLoader(config.WithFile(file)), func() interface{} {
+		return &watchConfig{}
+	}, config.WithPolling(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	var events int32
+	w.Subscribe("redis", func(config.Event) {
+		atomic.AddInt32(&events, 1)
+	})
+
+	if err := ioutil.WriteFile(file, []byte("env: dev\nredis:\n  pool_size: 20\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = w.Reload()
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&events); n != 1 {
+		t.Errorf("%d events for one change", n)
+	}
+}
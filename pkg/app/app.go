@@ -0,0 +1,462 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/GaVender/era/pkg/log"
+)
+
+type (
+	// Component is started after everything in DependsOn and stopped before it.
+	// Start must not block, long-running work goes into Run, which is canceled on shutdown.
+	Component struct {
+		Name      string
+		DependsOn []string
+		Start     func(ctx context.Context) error
+		Run       func(ctx context.Context) error
+		Stop      func(ctx context.Context) error
+		Timeout   time.Duration
+	}
+
+	App struct {
+		name         string
+		logger       log.Logger
+		startTimeout time.Duration
+		stopTimeout  time.Duration
+		signals      []os.Signal
+		mu           sync.Mutex
+		components   []Component
+		started      []Component
+		runs         map[string]chan struct{}
+		cancel       context.CancelFunc
+		failed       chan error
+	}
+
+	ComponentError struct {
+		Name  string
+		Phase string
+		Err   error
+	}
+
+	Errors []error
+
+	Option func(*App)
+)
+
+const (
+	PhaseStart = "start"
+	PhaseRun   = "run"
+	PhaseStop  = "stop"
+
+	defaultStartTimeout = 30 * time.Second
+	defaultStopTimeout  = 10 * time.Second
+)
+
+var (
+	ErrDuplicateComponent = errors.New("duplicate component")
+	ErrUnknownDependency  = errors.New("unknown dependency")
+	ErrDependencyCycle    = errors.New("dependency cycle")
+	ErrTimeout            = errors.New("component timeout")
+	ErrPanic              = errors.New("component panic")
+)
+
+func New(name string, opts ...Option) *App {
+	a := &App{
+		name:         name,
+		startTimeout: defaultStartTimeout,
+		stopTimeout:  defaultStopTimeout,
+		signals:      []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		runs:         make(map[string]chan struct{}),
+		failed:       make(chan error, 1),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.logger == nil {
+		a.logger = log.NullLogger{}
+	}
+
+	return a
+}
+
+func WithLogger(logger log.Logger) Option {
+	return func(a *App) {
+		a.logger = logger
+	}
+}
+
+func WithStartTimeout(timeout time.Duration) Option {
+	return func(a *App) {
+		a.startTimeout = timeout
+	}
+}
+
+// WithStopTimeout is the default per-component stop timeout, Component.Timeout overrides it.
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(a *App) {
+		a.stopTimeout = timeout
+	}
+}
+
+func WithSignals(signals ...os.Signal) Option {
+	return func(a *App) {
+		a.signals = signals
+	}
+}
+
+// Closer adapts the func() closers returned by the era constructors.
+func Closer(name string, closer func(), dependsOn ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Stop: func(ctx context.Context) error {
+			closer()
+			return nil
+		},
+	}
+}
+
+// CloserE adapts the func() error closers returned by the era *E constructors.
+func CloserE(name string, closer func() error, dependsOn ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Stop: func(ctx context.Context) error {
+			return closer()
+		},
+	}
+}
+
+func (a *App) Register(components ...Component) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, c := range components {
+		for _, registered := range a.components {
+			if registered.Name == c.Name {
+				return fmt.Errorf("%w: %s", ErrDuplicateComponent, c.Name)
+			}
+		}
+		a.components = append(a.components, c)
+	}
+
+	return nil
+}
+
+// Run starts every component, blocks until ctx is done, a signal arrives or a Run fails,
+// then stops the started components in reverse order. A signal during Start aborts it.
+func (a *App) Run(ctx context.Context) error {
+	sig := make(chan os.Signal, 1)
+	if len(a.signals) > 0 {
+		signal.Notify(sig, a.signals...)
+		defer signal.Stop(sig)
+	}
+
+	startCtx, cancelStart := context.WithCancel(ctx)
+	defer cancelStart()
+
+	started := make(chan error, 1)
+	go func() {
+		started <- a.Start(startCtx)
+	}()
+
+	select {
+	case err := <-started:
+		if err != nil {
+			return err
+		}
+	case s := <-sig:
+		a.logger.Infof("%s: receive signal %s while starting, shutting down", a.name, s.String())
+		cancelStart()
+		if err := <-started; err != nil {
+			return err
+		}
+		return a.Stop(context.Background())
+	}
+
+	var errs Errors
+	select {
+	case s := <-sig:
+		a.logger.Infof("%s: receive signal %s, shutting down", a.name, s.String())
+	case <-ctx.Done():
+		a.logger.Infof("%s: context done, shutting down", a.name)
+	case err := <-a.failed:
+		a.logger.Errorf("%s: %s, shutting down", a.name, err.Error())
+		errs = append(errs, err)
+	}
+
+	if err := a.Stop(context.Background()); err != nil {
+		errs = append(errs, err.(Errors)...)
+	}
+
+	return errs.err()
+}
+
+func (a *App) Start(ctx context.Context) error {
+	a.mu.Lock()
+	ordered, err := a.order()
+	a.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	for _, c := range ordered {
+		if err := ctx.Err(); err != nil {
+			return a.rollback(&ComponentError{Name: c.Name, Phase: PhaseStart, Err: err})
+		}
+
+		if c.Start != nil {
+			startCtx, cancelStart := context.WithTimeout(ctx, a.startTimeout)
+			err := call(startCtx, c, PhaseStart, c.Start)
+			cancelStart()
+
+			if err != nil {
+				return a.rollback(err)
+			}
+		}
+
+		a.mu.Lock()
+		a.started = append(a.started, c)
+		a.mu.Unlock()
+		a.logger.Infof("%s: %s started", a.name, c.Name)
+
+		if c.Run != nil {
+			done := make(chan struct{})
+			a.mu.Lock()
+			a.runs[c.Name] = done
+			a.mu.Unlock()
+			go a.run(runCtx, c, done)
+		}
+	}
+
+	return nil
+}
+
+// rollback stops what Start has started so far after err.
+func (a *App) rollback(err error) error {
+	a.logger.Errorf("%s: %s", a.name, err.Error())
+	errs := Errors{err}
+	if stopErr := a.Stop(context.Background()); stopErr != nil {
+		errs = append(errs, stopErr.(Errors)...)
+	}
+
+	return errs
+}
+
+// Stop stops the started components in reverse order, each within its own timeout
+// once its Run has returned, so nothing it depends on is stopped while it still runs.
+// A hung component is reported and skipped so the ones it depends on still get stopped.
+func (a *App) Stop(ctx context.Context) error {
+	if a.cancel != nil {
+		a.cancel()
+	}
+
+	a.mu.Lock()
+	started, runs := a.started, a.runs
+	a.started, a.runs = nil, make(map[string]chan struct{})
+	a.mu.Unlock()
+
+	var errs Errors
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		if done, ok := runs[c.Name]; ok {
+			if err := a.wait(ctx, c, done); err != nil {
+				a.logger.Errorf("%s: %s", a.name, err.Error())
+				errs = append(errs, err)
+			}
+		}
+		if c.Stop == nil {
+			continue
+		}
+
+		stopCtx, cancel := context.WithTimeout(ctx, a.timeout(c))
+		err := call(stopCtx, c, PhaseStop, c.Stop)
+		cancel()
+
+		if err != nil {
+			a.logger.Errorf("%s: %s", a.name, err.Error())
+			errs = append(errs, err)
+			continue
+		}
+		a.logger.Infof("%s: %s stopped", a.name, c.Name)
+	}
+
+	return errs.err()
+}
+
+func (a *App) timeout(c Component) time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+
+	return a.stopTimeout
+}
+
+// wait waits for the Run of c to close done after its context was canceled.
+func (a *App) wait(ctx context.Context, c Component, done chan struct{}) error {
+	waitCtx, cancel := context.WithTimeout(ctx, a.timeout(c))
+	defer cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-waitCtx.Done():
+		return &ComponentError{Name: c.Name, Phase: PhaseRun, Err: fmt.Errorf("%w: %s", ErrTimeout, waitCtx.Err().Error())}
+	}
+}
+
+func (a *App) run(ctx context.Context, c Component, done chan struct{}) {
+	defer close(done)
+
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w: %v", ErrPanic, r)
+			}
+		}()
+		err = c.Run(ctx)
+	}()
+
+	if err != nil && ctx.Err() == nil {
+		select {
+		case a.failed <- &ComponentError{Name: c.Name, Phase: PhaseRun, Err: err}:
+		default:
+		}
+	}
+}
+
+func (a *App) order() ([]Component, error) {
+	index := make(map[string]int, len(a.components))
+	for i, c := range a.components {
+		index[c.Name] = i
+	}
+
+	for _, c := range a.components {
+		for _, dep := range c.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, c.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		ordered = make([]Component, 0, len(a.components))
+		state   = make([]int, len(a.components))
+		visit   func(i int, path []string) error
+	)
+
+	visit = func(i int, path []string) error {
+		c := a.components[i]
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path, c.Name), " -> "))
+		}
+
+		state[i] = visiting
+		for _, dep := range c.DependsOn {
+			if err := visit(index[dep], append(path, c.Name)); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		ordered = append(ordered, c)
+		return nil
+	}
+
+	for i := range a.components {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+func call(ctx context.Context, c Component, phase string, fn func(context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("%w: %v", ErrPanic, r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return &ComponentError{Name: c.Name, Phase: phase, Err: err}
+		}
+		return nil
+	case <-ctx.Done():
+		return &ComponentError{Name: c.Name, Phase: phase, Err: fmt.Errorf("%w: %s", ErrTimeout, ctx.Err().Error())}
+	}
+}
+
+func (e *ComponentError) Error() string {
+	return e.Name + " " + e.Phase + ": " + e.Err.Error()
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
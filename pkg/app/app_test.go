@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) component(name string, deps ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: deps,
+		Start: func(ctx context.Context) error {
+			r.add("start " + name)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+}
+
+func TestApp(t *testing.T) {
+	tests := []struct {
+		name       string
+		components func(r *recorder) []Component
+		wantCalls  []string
+		wantErr    error
+		wantFailed string
+	}{
+		{
+			name: "dependency order",
+			components: func(r *recorder) []Component {
+				return []Component{
+					r.component("http", "redis", "mysql"),
+					r.component("redis", "logger"),
+					r.component("mysql", "logger"),
+					r.component("logger"),
+				}
+			},
+			wantCalls: []string{
+				"start logger", "start redis", "start mysql", "start http",
+				"stop http", "stop mysql", "stop redis", "stop logger",
+			},
+		},
+		{
+			name: "hung component",
+			components: func(r *recorder) []Component {
+				hung := r.component("mysql", "logger")
+				hung.Timeout = 10 * time.Millisecond
+				hung.Stop = func(ctx context.Context) error {
+					time.Sleep(time.Second)
+					return nil
+				}
+				return []Component{r.component("logger"), hung}
+			},
+			wantCalls:  []string{"start logger", "start mysql", "stop logger"},
+			wantErr:    ErrTimeout,
+			wantFailed: "mysql",
+		},
+		{
+			name: "run drains before its dependencies stop",
+			components: func(r *recorder) []Component {
+				consumer := r.component("consumer", "mysql")
+				consumer.Run = func(ctx context.Context) error {
+					<-ctx.Done()
+					time.Sleep(5 * time.Millisecond)
+					r.add("drain consumer")
+					return nil
+				}
+				return []Component{r.component("mysql"), consumer}
+			},
+			wantCalls: []string{"start mysql", "start consumer", "drain consumer", "stop consumer", "stop mysql"},
+		},
+		{
+			name: "stuck run",
+			components: func(r *recorder) []Component {
+				stuck := r.component("consumer", "logger")
+				stuck.Timeout = 10 * time.Millisecond
+				stuck.Run = func(ctx context.Context) error {
+					time.Sleep(time.Second)
+					return nil
+				}
+				return []Component{r.component("logger"), stuck}
+			},
+			wantCalls:  []string{"start logger", "start consumer", "stop consumer", "stop logger"},
+			wantErr:    ErrTimeout,
+			wantFailed: "consumer",
+		},
+		{
+			name: "start failure rolls back",
+			components: func(r *recorder) []Component {
+				broken := r.component("redis", "logger")
+				broken.Start = func(ctx context.Context) error {
+					return errors.New("dial tcp: connection refused")
+				}
+				return []Component{r.component("logger"), broken, r.component("http", "redis")}
+			},
+			wantCalls:  []string{"start logger", "stop logger"},
+			wantFailed: "redis",
+		},
+		{
+			name: "panicking closer",
+			components: func(r *recorder) []Component {
+				return []Component{r.component("logger"), Closer("tracer", func() {
+					panic("tracer close")
+				}, "logger")}
+			},
+			wantCalls:  []string{"start logger", "stop logger"},
+			wantErr:    ErrPanic,
+			wantFailed: "tracer",
+		},
+		{
+			name: "dependency cycle",
+			components: func(r *recorder) []Component {
+				return []Component{r.component("a", "b"), r.component("b", "a")}
+			},
+			wantErr: ErrDependencyCycle,
+		},
+		{
+			name: "unknown dependency",
+			components: func(r *recorder) []Component {
+				return []Component{r.component("a", "b")}
+			},
+			wantErr: ErrUnknownDependency,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			a := New("test", WithSignals())
+			if err := a.Register(tt.components(r)...); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			err := a.Run(ctx)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && tt.wantFailed == "" && err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+
+			if tt.wantFailed != "" {
+				var ce *ComponentError
+				if !errors.As(err, &ce) || ce.Name != tt.wantFailed {
+					t.Errorf("Run() error = %v, want failed component %s", err, tt.wantFailed)
+				}
+			}
+
+			if !reflect.DeepEqual(r.calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", r.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAppRunFailure(t *testing.T) {
+	stopped := make(chan bool, 1)
+	a := New("test", WithSignals())
+	_ = a.Register(Component{
+		Name: "server",
+		Run: func(ctx context.Context) error {
+			return errors.New("listen tcp :80: bind: permission denied")
+		},
+		Stop: func(ctx context.Context) error {
+			stopped <- true
+			return nil
+		},
+	})
+
+	err := a.Run(context.Background())
+	var ce *ComponentError
+	if !errors.As(err, &ce) || ce.Name != "server" || ce.Phase != PhaseRun {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("server not stopped")
+	}
+}
+
+func TestAppSignalDuringStart(t *testing.T) {
+	r := &recorder{}
+	slow := r.component("http", "logger")
+	slow.Start = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	a := New("test", WithSignals(syscall.SIGUSR1))
+	if err := a.Register(r.component("logger"), slow); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	}()
+
+	err := a.Run(context.Background())
+	var ce *ComponentError
+	if !errors.As(err, &ce) || ce.Name != "http" || ce.Phase != PhaseStart {
+		t.Errorf("Run() error = %v", err)
+	}
+	if want := []string{"start logger", "stop logger"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("calls = %v, want %v", r.calls, want)
+	}
+}
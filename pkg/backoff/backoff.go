@@ -0,0 +1,86 @@
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type (
+	// Policy is an exponential backoff, the nth retry waits Base*2^(n-1) capped by Max.
+	Policy struct {
+		Attempts int
+		Base     time.Duration
+		Max      time.Duration
+		Jitter   bool
+	}
+)
+
+var (
+	rnd   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rndMu sync.Mutex
+)
+
+// Backoff returns the wait before the given retry, counted from 1.
+func (p Policy) Backoff(retry int) time.Duration {
+	if p.Base <= 0 || retry <= 0 {
+		return 0
+	}
+
+	d := p.Base
+	for i := 1; i < retry; i++ {
+		if p.Max > 0 && d >= p.Max || d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+	}
+
+	if p.Max > 0 && d > p.Max {
+		d = p.Max
+	}
+
+	if p.Jitter && d > 0 {
+		rndMu.Lock()
+		d = time.Duration(rnd.Int63n(int64(d)) + 1)
+		rndMu.Unlock()
+	}
+
+	return d
+}
+
+// Retry calls fn until it succeeds, the attempts run out or ctx is done, and returns the last error.
+// onRetry, if not nil, is called before every wait.
+func Retry(ctx context.Context, p Policy, fn func(attempt int) error, onRetry func(attempt int, err error, wait time.Duration)) error {
+	attempts := p.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(attempt); err == nil {
+			return nil
+		}
+
+		if attempt == attempts {
+			break
+		}
+
+		wait := p.Backoff(attempt)
+		if onRetry != nil {
+			onRetry(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
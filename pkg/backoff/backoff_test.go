@@ -0,0 +1,84 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		retry  int
+		want   time.Duration
+	}{
+		{name: "zero base", policy: Policy{}, retry: 3, want: 0},
+		{name: "first retry", policy: Policy{Base: 10 * time.Millisecond}, retry: 1, want: 10 * time.Millisecond},
+		{name: "exponential", policy: Policy{Base: 10 * time.Millisecond}, retry: 4, want: 80 * time.Millisecond},
+		{name: "capped", policy: Policy{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}, retry: 4, want: 50 * time.Millisecond},
+		{name: "no overflow", policy: Policy{Base: time.Second}, retry: 100, want: time.Second << 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Backoff(tt.retry)
+			if tt.name == "no overflow" {
+				if got <= 0 {
+					t.Errorf("Backoff() = %v, want positive", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Backoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	p := Policy{Base: 10 * time.Millisecond, Jitter: true}
+	for i := 0; i < 100; i++ {
+		if d := p.Backoff(3); d <= 0 || d > 40*time.Millisecond {
+			t.Fatalf("jittered Backoff() = %v", d)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errDown := errors.New("down")
+
+	var calls int
+	err := Retry(context.Background(), Policy{Attempts: 3, Base: time.Millisecond}, func(attempt int) error {
+		calls++
+		if attempt < 3 {
+			return errDown
+		}
+		return nil
+	}, nil)
+	if err != nil || calls != 3 {
+		t.Fatalf("Retry() = %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	var retries int
+	err = Retry(context.Background(), Policy{Attempts: 2}, func(int) error {
+		calls++
+		return errDown
+	}, func(int, error, time.Duration) {
+		retries++
+	})
+	if !errors.Is(err, errDown) || calls != 2 || retries != 1 {
+		t.Fatalf("Retry() = %v after %d calls, %d retries", err, calls, retries)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	err = Retry(ctx, Policy{Attempts: 5, Base: time.Hour}, func(int) error {
+		calls++
+		return errDown
+	}, nil)
+	if !errors.Is(err, errDown) || calls != 1 {
+		t.Fatalf("Retry() with canceled ctx = %v after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,351 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/GaVender/era/pkg/log"
+)
+
+type (
+	State int
+
+	// Config opens the circuit when, over the last Window, at least MinRequests calls were made
+	// and the share of failed calls reaches ErrorRate or the share of calls slower than SlowCall reaches SlowCallRate.
+	// After OpenTimeout it lets HalfOpenRequests calls through, which close it again when they all succeed.
+	// A probe not reported within ProbeTimeout, OpenTimeout by default, counts as failed.
+	Config struct {
+		Window           time.Duration
+		Buckets          int
+		MinRequests      int
+		ErrorRate        float64
+		SlowCall         time.Duration
+		SlowCallRate     float64
+		OpenTimeout      time.Duration
+		HalfOpenRequests int
+		ProbeTimeout     time.Duration
+	}
+
+	Breaker struct {
+		name       string
+		cfg        Config
+		logger     log.Logger
+		now        func() time.Time
+		width      time.Duration
+		mu         sync.Mutex
+		state      State
+		generation uint64
+		buckets    []bucket
+		openedAt   time.Time
+		probedAt   time.Time
+		probes     int
+		succeeded  int
+	}
+
+	// Error is returned without calling through while the circuit is open, errors.Is matches it against ErrCircuitOpen.
+	Error struct {
+		Name  string
+		State State
+	}
+
+	bucket struct {
+		epoch    int64
+		total    int
+		failures int
+		slow     int
+	}
+
+	Option func(*Breaker)
+)
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+
+	defaultWindow           = 10 * time.Second
+	defaultBuckets          = 10
+	defaultMinRequests      = 20
+	defaultErrorRate        = 0.5
+	defaultOpenTimeout      = 10 * time.Second
+	defaultHalfOpenRequests = 1
+
+	resultSuccess  = "success"
+	resultFailure  = "failure"
+	resultRejected = "rejected"
+	resultCanceled = "canceled"
+)
+
+var (
+	ErrCircuitOpen = errors.New("circuit open")
+)
+
+func New(name string, cfg Config, opts ...Option) *Breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = defaultBuckets
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = defaultMinRequests
+	}
+	if cfg.ErrorRate <= 0 {
+		cfg.ErrorRate = defaultErrorRate
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultOpenTimeout
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = defaultHalfOpenRequests
+	}
+	if cfg.ProbeTimeout <= 0 {
+		cfg.ProbeTimeout = cfg.OpenTimeout
+	}
+
+	b := &Breaker{
+		name:    name,
+		cfg:     cfg,
+		now:     time.Now,
+		width:   cfg.Window / time.Duration(cfg.Buckets),
+		buckets: make([]bucket, cfg.Buckets),
+	}
+	if b.width <= 0 {
+		b.width = 1
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.logger == nil {
+		b.logger = log.NullLogger{}
+	}
+
+	metricsBreakerStateGauge.WithLabelValues(name).Set(float64(StateClosed))
+	return b
+}
+
+// WithLogger logs the state changes of the breaker.
+func WithLogger(logger log.Logger) Option {
+	return func(b *Breaker) {
+		b.logger = logger
+	}
+}
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(b *Breaker) {
+		b.now = now
+	}
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expire(b.now())
+	return b.state
+}
+
+// Allow reserves a call, which must be reported through done once it returns,
+// or fails with an *Error when the circuit is open. A call reported with context.Canceled
+// or context.DeadlineExceeded was given up by the caller, it counts neither as a failure nor as a success.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.expire(now)
+
+	switch b.state {
+	case StateOpen:
+		return nil, b.reject()
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return nil, b.reject()
+		}
+		if b.probes == 0 {
+			b.probedAt = now
+		}
+		b.probes++
+	}
+
+	generation := b.generation
+	return func(err error) {
+		b.done(generation, now, err)
+	}, nil
+}
+
+// Do calls fn through the breaker, a nil breaker calls fn directly.
+func (b *Breaker) Do(fn func() error) error {
+	if b == nil {
+		return fn()
+	}
+
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	done(err)
+	return err
+}
+
+func (b *Breaker) done(generation uint64, start time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		metricsBreakerCallCounter.WithLabelValues(b.name, resultCanceled).Inc()
+		// a probe given up on lets another one through
+		if generation == b.generation && b.state == StateHalfOpen {
+			b.probes--
+		}
+		return
+	}
+
+	failed := err != nil
+	slow := b.cfg.SlowCall > 0 && now.Sub(start) >= b.cfg.SlowCall
+
+	if failed {
+		metricsBreakerCallCounter.WithLabelValues(b.name, resultFailure).Inc()
+	} else {
+		metricsBreakerCallCounter.WithLabelValues(b.name, resultSuccess).Inc()
+	}
+
+	// the calls allowed before the last state change don't count
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateHalfOpen:
+		if failed || slow && b.cfg.SlowCallRate > 0 {
+			b.transit(StateOpen, now)
+			return
+		}
+
+		b.succeeded++
+		if b.succeeded >= b.cfg.HalfOpenRequests {
+			b.transit(StateClosed, now)
+		}
+	case StateClosed:
+		bk := b.bucket(now)
+		bk.total++
+		if failed {
+			bk.failures++
+		}
+		if slow {
+			bk.slow++
+		}
+
+		if b.tripped(now) {
+			b.transit(StateOpen, now)
+		}
+	}
+}
+
+func (b *Breaker) tripped(now time.Time) bool {
+	var total, failures, slow int
+	oldest := b.epoch(now) - int64(len(b.buckets)) + 1
+	for _, bk := range b.buckets {
+		if bk.epoch < oldest {
+			continue
+		}
+		total += bk.total
+		failures += bk.failures
+		slow += bk.slow
+	}
+
+	if total < b.cfg.MinRequests {
+		return false
+	}
+
+	if float64(failures)/float64(total) >= b.cfg.ErrorRate {
+		return true
+	}
+
+	return b.cfg.SlowCallRate > 0 && float64(slow)/float64(total) >= b.cfg.SlowCallRate
+}
+
+// expire turns an open circuit half-open once OpenTimeout has passed,
+// and opens a half-open one again when its probes haven't all reported within ProbeTimeout.
+func (b *Breaker) expire(now time.Time) {
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+			b.transit(StateHalfOpen, now)
+		}
+	case StateHalfOpen:
+		if b.succeeded < b.probes && now.Sub(b.probedAt) >= b.cfg.ProbeTimeout {
+			b.transit(StateOpen, now)
+		}
+	}
+}
+
+func (b *Breaker) transit(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.probes, b.succeeded = 0, 0
+
+	switch to {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		for i := range b.buckets {
+			b.buckets[i] = bucket{}
+		}
+	}
+
+	metricsBreakerStateGauge.WithLabelValues(b.name).Set(float64(to))
+	b.logger.Warnf("breaker %s: %s -> %s", b.name, from.String(), to.String())
+}
+
+func (b *Breaker) reject() error {
+	metricsBreakerCallCounter.WithLabelValues(b.name, resultRejected).Inc()
+	return &Error{Name: b.name, State: b.state}
+}
+
+func (b *Breaker) bucket(now time.Time) *bucket {
+	epoch := b.epoch(now)
+	bk := &b.buckets[int(epoch%int64(len(b.buckets)))]
+	if bk.epoch != epoch {
+		*bk = bucket{epoch: epoch}
+	}
+
+	return bk
+}
+
+func (b *Breaker) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(b.width)
+}
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+func (e *Error) Error() string {
+	return "breaker " + e.Name + ": " + ErrCircuitOpen.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
@@ -0,0 +1,128 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestBreaker(t *testing.T) {
+	errDown := errors.New("down")
+	cfg := Config{MinRequests: 4, ErrorRate: 0.5, SlowCall: 100 * time.Millisecond, SlowCallRate: 0.5, OpenTimeout: time.Second}
+
+	tests := []struct {
+		name      string
+		calls     []error
+		slow      bool
+		wait      time.Duration
+		probing   bool
+		probe     error
+		wantState State
+	}{
+		{name: "healthy", calls: []error{nil, nil, errDown, nil}, wantState: StateClosed},
+		{name: "canceled calls", calls: []error{context.Canceled, context.DeadlineExceeded, context.Canceled, errDown}, wantState: StateClosed},
+		{name: "too few calls", calls: []error{errDown, errDown, errDown}, wantState: StateClosed},
+		{name: "error rate", calls: []error{nil, errDown, nil, errDown}, wantState: StateOpen},
+		{name: "slow calls", calls: []error{nil, nil, nil, nil}, slow: true, wantState: StateOpen},
+		{name: "half-open", calls: []error{errDown, errDown, errDown, errDown}, wait: time.Second, wantState: StateHalfOpen},
+		{name: "probe succeeds", calls: []error{errDown, errDown, errDown, errDown}, wait: time.Second, probing: true, wantState: StateClosed},
+		{name: "probe canceled", calls: []error{errDown, errDown, errDown, errDown}, wait: time.Second, probing: true, probe: context.Canceled, wantState: StateHalfOpen},
+		{name: "probe fails", calls: []error{errDown, errDown, errDown, errDown}, wait: time.Second, probing: true, probe: errDown, wantState: StateOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &clock{now: time.Unix(1585000000, 0)}
+			b := New(tt.name, cfg, WithClock(c.Now))
+
+			for _, err := range tt.calls {
+				done, allowErr := b.Allow()
+				if allowErr != nil {
+					t.Fatalf("Allow() = %v", allowErr)
+				}
+				if tt.slow {
+					c.now = c.now.Add(200 * time.Millisecond)
+				}
+				done(err)
+			}
+
+			c.now = c.now.Add(tt.wait)
+			if tt.probing {
+				if err := b.Do(func() error { return tt.probe }); err != tt.probe {
+					t.Fatalf("probe = %v", err)
+				}
+			}
+
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("state = %s, want %s", got, tt.wantState)
+			}
+
+			_, err := b.Allow()
+			if open := tt.wantState == StateOpen; open != errors.Is(err, ErrCircuitOpen) {
+				t.Errorf("Allow() = %v in state %s", err, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpenProbes(t *testing.T) {
+	c := &clock{now: time.Unix(1585000000, 0)}
+	b := New("probes", Config{MinRequests: 1, OpenTimeout: time.Second}, WithClock(c.Now))
+
+	b.Do(func() error { return errors.New("down") })
+	c.now = c.now.Add(time.Second)
+
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second probe = %v, want ErrCircuitOpen", err)
+	}
+
+	var e *Error
+	if _, err := b.Allow(); !errors.As(err, &e) || e.Name != "probes" || e.State != StateHalfOpen {
+		t.Errorf("error = %#v", err)
+	}
+
+	done(nil)
+	if b.State() != StateClosed {
+		t.Errorf("state = %s", b.State())
+	}
+}
+
+func TestBreakerLostProbe(t *testing.T) {
+	c := &clock{now: time.Unix(1585000000, 0)}
+	b := New("lost", Config{MinRequests: 1, OpenTimeout: time.Second, ProbeTimeout: 100 * time.Millisecond}, WithClock(c.Now))
+
+	b.Do(func() error { return errors.New("down") })
+	c.now = c.now.Add(time.Second)
+
+	late, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+
+	c.now = c.now.Add(100 * time.Millisecond)
+	if b.State() != StateOpen {
+		t.Errorf("state = %s with a lost probe", b.State())
+	}
+	late(nil)
+	if b.State() != StateOpen {
+		t.Errorf("state = %s after a late report", b.State())
+	}
+
+	c.now = c.now.Add(time.Second)
+	if err := b.Do(func() error { return nil }); err != nil || b.State() != StateClosed {
+		t.Errorf("probe = %v, state = %s", err, b.State())
+	}
+}
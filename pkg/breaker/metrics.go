@@ -0,0 +1,31 @@
+package breaker
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	namespace = "era"
+	subsystem = "breaker"
+
+	metricsBreakerStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "state",
+		Help:      "state of the breaker, 0 closed, 1 open, 2 half-open",
+	}, []string{
+		"name",
+	})
+
+	metricsBreakerCallCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "call_total",
+		Help:      "total number of calls through the breaker by result",
+	}, []string{
+		"name",
+		"result",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(metricsBreakerStateGauge, metricsBreakerCallCounter)
+}
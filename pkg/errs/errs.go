@@ -0,0 +1,21 @@
+package errs
+
+type (
+	// Error wraps the underlying failure, errors.Is matches it against its Kind, e.g. mysql.ErrConnect.
+	Error struct {
+		Kind error
+		Err  error
+	}
+)
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
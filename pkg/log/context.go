@@ -0,0 +1,24 @@
+package log
+
+import "context"
+
+type loggerKey struct{}
+
+// IntoContext attaches a request-scoped logger, the era clients log through it when present.
+func IntoContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+func FromContext(ctx context.Context) Logger {
+	return FromContextOr(ctx, NullLogger{})
+}
+
+func FromContextOr(ctx context.Context, fallback Logger) Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(Logger); ok && logger != nil {
+			return logger
+		}
+	}
+
+	return fallback
+}
@@ -8,11 +8,26 @@ type Logger interface {
 
 	Debugf(format string, v ...interface{})
 	Infof(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
 	Errorf(format string, v ...interface{})
 	Panicf(format string, v ...interface{})
 
 	ContextDebugf(ctx context.Context, format string, v ...interface{})
 	ContextInfof(ctx context.Context, format string, v ...interface{})
+	ContextWarnf(ctx context.Context, format string, v ...interface{})
 	ContextErrorf(ctx context.Context, format string, v ...interface{})
 	ContextPanicf(ctx context.Context, format string, v ...interface{})
+
+	DebugField(msg string, fields ...Field)
+	InfoField(msg string, fields ...Field)
+	WarnField(msg string, fields ...Field)
+	ErrorField(msg string, fields ...Field)
+
+	ContextDebugField(ctx context.Context, msg string, fields ...Field)
+	ContextInfoField(ctx context.Context, msg string, fields ...Field)
+	ContextWarnField(ctx context.Context, msg string, fields ...Field)
+	ContextErrorField(ctx context.Context, msg string, fields ...Field)
+
+	With(fields ...Field) Logger
+	Named(name string) Logger
 }
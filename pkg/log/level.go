@@ -0,0 +1,285 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type (
+	// Levels holds the base level and the per-named-logger overrides, an override named "mysql"
+	// applies to "era.mysql" and its children unless a longer override matches.
+	Levels struct {
+		base     zap.AtomicLevel
+		mu       sync.RWMutex
+		named    map[string]zapcore.Level
+		reverts  map[string]*revert
+		minNamed int32
+		hasNamed int32
+	}
+
+	revert struct {
+		timer    *time.Timer
+		previous *zapcore.Level
+	}
+
+	levelCore struct {
+		zapcore.Core
+		levels *Levels
+	}
+
+	levelPayload struct {
+		Name  string            `json:"name,omitempty"`
+		Level string            `json:"level,omitempty"`
+		TTL   string            `json:"ttl,omitempty"`
+		Named map[string]string `json:"named,omitempty"`
+		Error string            `json:"error,omitempty"`
+	}
+)
+
+var (
+	ErrInvalidLevel = errors.New("invalid log level")
+)
+
+func NewLevels(base zap.AtomicLevel) *Levels {
+	return &Levels{
+		base:    base,
+		named:   make(map[string]zapcore.Level),
+		reverts: make(map[string]*revert),
+	}
+}
+
+func (l *Levels) Base() zap.AtomicLevel {
+	return l.base
+}
+
+// Level returns the effective level of the named logger, an empty name is the base level.
+func (l *Levels) Level(name string) zapcore.Level {
+	if atomic.LoadInt32(&l.hasNamed) == 0 || len(name) == 0 {
+		return l.base.Level()
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.level(name)
+}
+
+// Named returns a copy of the overrides.
+func (l *Levels) Named() map[string]zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	named := make(map[string]zapcore.Level, len(l.named))
+	for k, v := range l.named {
+		named[k] = v
+	}
+
+	return named
+}
+
+// SetLevel sets the level of the named logger, an empty name is the base level.
+// A positive ttl reverts the change once it expires.
+func (l *Levels) SetLevel(name, level string, ttl time.Duration) error {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return ErrInvalidLevel
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var previous *zapcore.Level
+	if r, ok := l.reverts[name]; ok {
+		r.timer.Stop()
+		previous = r.previous
+		delete(l.reverts, name)
+	} else if len(name) == 0 {
+		base := l.base.Level()
+		previous = &base
+	} else if cur, ok := l.named[name]; ok {
+		previous = &cur
+	}
+
+	l.set(name, &lvl)
+
+	if ttl > 0 {
+		r := &revert{previous: previous}
+		r.timer = time.AfterFunc(ttl, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			if l.reverts[name] != r {
+				return
+			}
+			delete(l.reverts, name)
+			l.set(name, r.previous)
+		})
+		l.reverts[name] = r
+	}
+
+	return nil
+}
+
+// Reset removes the override of the named logger, which follows the base level again.
+func (l *Levels) Reset(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r, ok := l.reverts[name]; ok {
+		r.timer.Stop()
+		delete(l.reverts, name)
+	}
+
+	if len(name) > 0 {
+		l.set(name, nil)
+	}
+}
+
+func (l *Levels) Enabled(name string, lvl zapcore.Level) bool {
+	return l.Level(name).Enabled(lvl)
+}
+
+// ServeHTTP reports the levels on GET and changes one on PUT with a JSON body like
+// {"name": "mysql", "level": "debug", "ttl": "10m"}, DELETE ?name=mysql removes the override.
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	enc := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req levelPayload
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = enc.Encode(levelPayload{Error: err.Error()})
+			return
+		}
+		// zap reads an empty level as info, a PUT without one must not reset the level.
+		if len(req.Level) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = enc.Encode(levelPayload{Error: ErrInvalidLevel.Error()})
+			return
+		}
+
+		var ttl time.Duration
+		if len(req.TTL) > 0 {
+			d, err := time.ParseDuration(req.TTL)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = enc.Encode(levelPayload{Error: err.Error()})
+				return
+			}
+			ttl = d
+		}
+
+		if err := l.SetLevel(req.Name, req.Level, ttl); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = enc.Encode(levelPayload{Error: err.Error()})
+			return
+		}
+	case http.MethodDelete:
+		l.Reset(r.URL.Query().Get("name"))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = enc.Encode(levelPayload{Error: "only GET, PUT and DELETE are supported"})
+		return
+	}
+
+	resp := levelPayload{Level: l.base.Level().String(), Named: make(map[string]string)}
+	if name := r.URL.Query().Get("name"); len(name) > 0 {
+		resp.Name, resp.Level = name, l.Level(name).String()
+	}
+	for k, v := range l.Named() {
+		resp.Named[k] = v.String()
+	}
+
+	_ = enc.Encode(resp)
+}
+
+// set must be called with mu held, a nil level removes the override.
+func (l *Levels) set(name string, lvl *zapcore.Level) {
+	if len(name) == 0 {
+		if lvl != nil {
+			l.base.SetLevel(*lvl)
+		}
+		return
+	}
+
+	if lvl == nil {
+		delete(l.named, name)
+	} else {
+		l.named[name] = *lvl
+	}
+
+	min := zapcore.FatalLevel
+	for _, v := range l.named {
+		if v < min {
+			min = v
+		}
+	}
+	atomic.StoreInt32(&l.minNamed, int32(min))
+
+	var has int32
+	if len(l.named) > 0 {
+		has = 1
+	}
+	atomic.StoreInt32(&l.hasNamed, has)
+}
+
+// level picks the longest override whose dotted segments appear in name.
+func (l *Levels) level(name string) zapcore.Level {
+	var (
+		dotted = "." + name + "."
+		match  string
+		lvl    = l.base.Level()
+	)
+
+	for n, v := range l.named {
+		if len(n) > len(match) && strings.Contains(dotted, "."+n+".") {
+			match, lvl = n, v
+		}
+	}
+
+	return lvl
+}
+
+func (l *Levels) min() zapcore.Level {
+	base := l.base.Level()
+	if atomic.LoadInt32(&l.hasNamed) == 0 {
+		return base
+	}
+
+	if named := zapcore.Level(atomic.LoadInt32(&l.minNamed)); named < base {
+		return named
+	}
+
+	return base
+}
+
+func newLevelCore(core zapcore.Core, levels *Levels) zapcore.Core {
+	return &levelCore{Core: core, levels: levels}
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.levels.min().Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.Enabled(entry.LoggerName, entry.Level) {
+		return ce
+	}
+
+	return c.Core.Check(entry, ce)
+}
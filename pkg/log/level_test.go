@@ -0,0 +1,105 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		named  map[string]string
+		logger string
+		level  zapcore.Level
+		want   bool
+	}{
+		{name: "base", logger: "era", level: zapcore.DebugLevel, want: false},
+		{name: "override", named: map[string]string{"mysql": "debug"}, logger: "era.mysql", level: zapcore.DebugLevel, want: true},
+		{name: "override child", named: map[string]string{"mysql": "debug"}, logger: "era.mysql.slow", level: zapcore.DebugLevel, want: true},
+		{name: "other logger", named: map[string]string{"mysql": "debug"}, logger: "era.redis", level: zapcore.DebugLevel, want: false},
+		{name: "longest wins", named: map[string]string{"mysql": "debug", "mysql.slow": "error"}, logger: "era.mysql.slow", level: zapcore.WarnLevel, want: false},
+		{name: "quieter override", named: map[string]string{"redis": "error"}, logger: "era.redis", level: zapcore.InfoLevel, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			levels := NewLevels(zap.NewAtomicLevel())
+			for name, lvl := range tt.named {
+				if err := levels.SetLevel(name, lvl, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l := zap.New(newLevelCore(core, levels))
+			if ce := l.Named(tt.logger).Check(tt.level, "msg"); ce != nil {
+				ce.Write()
+			}
+
+			if got := logs.Len() == 1; got != tt.want {
+				t.Errorf("logged = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelsTTL(t *testing.T) {
+	levels := NewLevels(zap.NewAtomicLevel())
+	if err := levels.SetLevel("", "debug", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := levels.SetLevel("mysql", "debug", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if levels.Level("") != zapcore.DebugLevel || levels.Level("era.mysql") != zapcore.DebugLevel {
+		t.Fatal("levels not set")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if levels.Level("") != zapcore.InfoLevel {
+		t.Errorf("base level = %s, want info", levels.Level(""))
+	}
+	if _, ok := levels.Named()["mysql"]; ok {
+		t.Error("mysql override not reverted")
+	}
+}
+
+func TestLevelsHandler(t *testing.T) {
+	levels := NewLevels(zap.NewAtomicLevel())
+
+	rec := httptest.NewRecorder()
+	levels.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"name":"mysql","level":"debug"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	levels.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	var got levelPayload
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Level != "info" || got.Named["mysql"] != "debug" {
+		t.Errorf("GET = %+v", got)
+	}
+
+	for _, body := range []string{`{"level":"loud"}`, `{"name":"mysql"}`, `{"name":"mysql","level":""}`} {
+		rec = httptest.NewRecorder()
+		levels.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s status = %d", body, rec.Code)
+		}
+	}
+	if lvl := levels.Level("mysql"); lvl != zapcore.DebugLevel {
+		t.Errorf("mysql level = %s after invalid PUTs", lvl)
+	}
+}
@@ -0,0 +1,22 @@
+package log
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	namespace = "era"
+	subsystem = "log"
+
+	metricsLogDroppedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "dropped_total",
+		Help:      "total number of log lines dropped by sampling or slow thresholds",
+	}, []string{
+		"logger",
+		"reason",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(metricsLogDroppedCounter)
+}
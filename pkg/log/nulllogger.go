@@ -8,11 +8,23 @@ func (n NullLogger) Error(msg string)
 func (n NullLogger) Print(v ...interface{})                                             {}
 func (n NullLogger) Debugf(format string, v ...interface{})                             {}
 func (n NullLogger) Infof(format string, v ...interface{})                              {}
+func (n NullLogger) Warnf(format string, v ...interface{})                              {}
 func (n NullLogger) Errorf(format string, v ...interface{})                             {}
 func (n NullLogger) Panicf(format string, v ...interface{})                             {}
 func (n NullLogger) ContextDebugf(ctx context.Context, format string, v ...interface{}) {}
 func (n NullLogger) ContextInfof(ctx context.Context, format string, v ...interface{})  {}
+func (n NullLogger) ContextWarnf(ctx context.Context, format string, v ...interface{})  {}
 func (n NullLogger) ContextErrorf(ctx context.Context, format string, v ...interface{}) {}
 func (n NullLogger) ContextPanicf(ctx context.Context, format string, v ...interface{}) {}
+func (n NullLogger) DebugField(msg string, fields ...Field)                             {}
+func (n NullLogger) InfoField(msg string, fields ...Field)                              {}
+func (n NullLogger) WarnField(msg string, fields ...Field)                              {}
+func (n NullLogger) ErrorField(msg string, fields ...Field)                             {}
+func (n NullLogger) ContextDebugField(ctx context.Context, msg string, fields ...Field) {}
+func (n NullLogger) ContextInfoField(ctx context.Context, msg string, fields ...Field)  {}
+func (n NullLogger) ContextWarnField(ctx context.Context, msg string, fields ...Field)  {}
+func (n NullLogger) ContextErrorField(ctx context.Context, msg string, fields ...Field) {}
+func (n NullLogger) With(fields ...Field) Logger                                        { return n }
+func (n NullLogger) Named(name string) Logger                                           { return n }
 
 var _ Logger = NullLogger{}
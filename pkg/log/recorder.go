@@ -0,0 +1,275 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type (
+	Entry struct {
+		Level   string
+		Logger  string
+		Message string
+		Fields  []Field
+		TraceID string
+	}
+
+	// TB is the part of testing.TB the recorder needs.
+	TB interface {
+		Helper()
+		Log(args ...interface{})
+		Errorf(format string, args ...interface{})
+	}
+
+	// Recorder keeps every line in memory so tests can assert on what the clients log,
+	// its children created by With and Named share the same entries.
+	Recorder struct {
+		recording *recording
+		name      string
+		fields    []Field
+	}
+
+	recording struct {
+		mu      sync.Mutex
+		entries []Entry
+		tb      TB
+	}
+)
+
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+	LevelPanic = "panic"
+)
+
+func NewRecorder() *Recorder {
+	return &Recorder{recording: &recording{}}
+}
+
+// NewTestLogger is a Recorder that also forwards every line to t.Log.
+func NewTestLogger(t TB) *Recorder {
+	return &Recorder{recording: &recording{tb: t}}
+}
+
+func (r *Recorder) Entries() []Entry {
+	r.recording.mu.Lock()
+	defer r.recording.mu.Unlock()
+
+	return append([]Entry(nil), r.recording.entries...)
+}
+
+func (r *Recorder) Filter(level string) []Entry {
+	var entries []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+func (r *Recorder) Reset() {
+	r.recording.mu.Lock()
+	r.recording.entries = nil
+	r.recording.mu.Unlock()
+}
+
+// Contains reports whether a line was logged at level with msg and at least the given fields.
+func (r *Recorder) Contains(level, msg string, fields ...Field) bool {
+	for _, e := range r.Filter(level) {
+		if e.Message == msg && e.has(fields) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Recorder) AssertContains(t TB, level, msg string, fields ...Field) bool {
+	t.Helper()
+
+	if r.Contains(level, msg, fields...) {
+		return true
+	}
+
+	var logged []string
+	for _, e := range r.Entries() {
+		logged = append(logged, e.String())
+	}
+	t.Errorf("no %s line %q with fields %v, logged:\n%s", level, msg, fields, strings.Join(logged, "\n"))
+	return false
+}
+
+func (r *Recorder) Error(msg string) {
+	r.record(nil, LevelError, msg, nil)
+}
+
+func (r *Recorder) Print(v ...interface{}) {
+	r.record(nil, LevelInfo, fmt.Sprint(v...), nil)
+}
+
+func (r *Recorder) Debugf(format string, v ...interface{}) {
+	r.record(nil, LevelDebug, fmt.Sprintf(format, v...), nil)
+}
+
+func (r *Recorder) Infof(format string, v ...interface{}) {
+	r.record(nil, LevelInfo, fmt.Sprintf(format, v...), nil)
+}
+
+func (r *Recorder) Warnf(format string, v ...interface{}) {
+	r.record(nil, LevelWarn, fmt.Sprintf(format, v...), nil)
+}
+
+func (r *Recorder) Errorf(format string, v ...interface{}) {
+	r.record(nil, LevelError, fmt.Sprintf(format, v...), nil)
+}
+
+func (r *Recorder) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	r.record(nil, LevelPanic, msg, nil)
+	panic(msg)
+}
+
+func (r *Recorder) ContextDebugf(ctx context.Context, format string, v ...interface{}) {
+	r.record(ctx, LevelDebug, fmt.Sprintf(format, v...), nil)
+}
+
+func (r *Recorder) ContextInfof(ctx context.Context, format string, v ...interface{}) {
+	r.record(ctx, LevelInfo, fmt.Sprintf(format, v...), nil)
+}
+
+func (r *Recorder) ContextWarnf(ctx context.Context, format string, v ...interface{}) {
+	r.record(ctx, LevelWarn, fmt.Sprintf(format, v...), nil)
+}
+
+func (r *Recorder) ContextErrorf(ctx context.Context, format string, v ...interface{}) {
+	r.record(ctx, LevelError, fmt.Sprintf(format, v...), nil)
+}
+
+func (r *Recorder) ContextPanicf(ctx context.Context, format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	r.record(ctx, LevelPanic, msg, nil)
+	panic(msg)
+}
+
+func (r *Recorder) DebugField(msg string, fields ...Field) {
+	r.record(nil, LevelDebug, msg, fields)
+}
+
+func (r *Recorder) InfoField(msg string, fields ...Field) {
+	r.record(nil, LevelInfo, msg, fields)
+}
+
+func (r *Recorder) WarnField(msg string, fields ...Field) {
+	r.record(nil, LevelWarn, msg, fields)
+}
+
+func (r *Recorder) ErrorField(msg string, fields ...Field) {
+	r.record(nil, LevelError, msg, fields)
+}
+
+func (r *Recorder) ContextDebugField(ctx context.Context, msg string, fields ...Field) {
+	r.record(ctx, LevelDebug, msg, fields)
+}
+
+func (r *Recorder) ContextInfoField(ctx context.Context, msg string, fields ...Field) {
+	r.record(ctx, LevelInfo, msg, fields)
+}
+
+func (r *Recorder) ContextWarnField(ctx context.Context, msg string, fields ...Field) {
+	r.record(ctx, LevelWarn, msg, fields)
+}
+
+func (r *Recorder) ContextErrorField(ctx context.Context, msg string, fields ...Field) {
+	r.record(ctx, LevelError, msg, fields)
+}
+
+func (r *Recorder) With(fields ...Field) Logger {
+	return &Recorder{
+		recording: r.recording,
+		name:      r.name,
+		fields:    append(append([]Field(nil), r.fields...), fields...),
+	}
+}
+
+func (r *Recorder) Named(name string) Logger {
+	if len(r.name) > 0 {
+		name = r.name + "." + name
+	}
+
+	return &Recorder{recording: r.recording, name: name, fields: r.fields}
+}
+
+func (r *Recorder) record(ctx context.Context, level, msg string, fields []Field) {
+	e := Entry{
+		Level:   level,
+		Logger:  r.name,
+		Message: msg,
+		Fields:  append(append([]Field(nil), r.fields...), fields...),
+	}
+	if info, ok := ExtractTrace(ctx); ok {
+		e.TraceID = info.TraceID
+	}
+
+	r.recording.mu.Lock()
+	r.recording.entries = append(r.recording.entries, e)
+	tb := r.recording.tb
+	r.recording.mu.Unlock()
+
+	if tb != nil {
+		tb.Helper()
+		tb.Log(e.String())
+	}
+}
+
+func (e Entry) String() string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(e.Level))
+	if len(e.Logger) > 0 {
+		b.WriteString(" " + e.Logger)
+	}
+	b.WriteString(" " + e.Message)
+	if len(e.TraceID) > 0 {
+		b.WriteString(" trace-id=" + e.TraceID)
+	}
+	for _, f := range e.Fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+
+	return b.String()
+}
+
+func (e Entry) has(fields []Field) bool {
+	for _, want := range fields {
+		found := false
+		for _, f := range e.Fields {
+			if f.Key == want.Key && fieldEqual(f.Value, want.Value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func fieldEqual(a, b interface{}) bool {
+	if ea, ok := a.(error); ok {
+		eb, ok := b.(error)
+		return ok && ea.Error() == eb.Error()
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+var _ Logger = &Recorder{}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+type fakeTB struct {
+	logs   []string
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Log(args ...interface{}) {
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	tracer := mocktracer.New()
+	sp := tracer.StartSpan("a")
+	ctx := opentracing.ContextWithSpan(context.Background(), sp)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Named("redis").With(String("db", "0")).ContextInfoField(ctx, "redis: get", Int("n", i))
+		}(i)
+	}
+	wg.Wait()
+	r.ErrorField("mysql: query", Err(errors.New("bad connection")))
+
+	if n := len(r.Entries()); n != 11 {
+		t.Fatalf("Entries() = %d, want 11", n)
+	}
+	if n := len(r.Filter(LevelInfo)); n != 10 {
+		t.Errorf("Filter(info) = %d, want 10", n)
+	}
+
+	e := r.Filter(LevelInfo)[0]
+	if e.Logger != "redis" || e.TraceID != strconv.Itoa(sp.Context().(mocktracer.MockSpanContext).TraceID) {
+		t.Errorf("entry = %+v", e)
+	}
+
+	r.AssertContains(t, LevelInfo, "redis: get", String("db", "0"), Int("n", 3))
+	r.AssertContains(t, LevelError, "mysql: query", Err(errors.New("bad connection")))
+
+	tb := &fakeTB{}
+	if r.AssertContains(tb, LevelWarn, "redis: get") || len(tb.errors) != 1 {
+		t.Errorf("AssertContains() on a missing line should fail")
+	}
+}
+
+func TestTestLogger(t *testing.T) {
+	tb := &fakeTB{}
+	NewTestLogger(tb).With(String("request_id", "1")).Infof("hello %s", "era")
+
+	if len(tb.logs) != 1 || tb.logs[0] != "INFO hello era request_id=1" {
+		t.Errorf("forwarded logs = %v", tb.logs)
+	}
+}
@@ -0,0 +1,327 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type (
+	// FileConfig rotates the file when it grows over MaxSize megabytes or every Interval,
+	// whichever comes first, and keeps at most MaxBackups rotated files younger than MaxAge.
+	FileConfig struct {
+		Path        string
+		MaxSize     int
+		Interval    time.Duration
+		MaxAge      time.Duration
+		MaxBackups  int
+		Compress    bool
+		ReopenOnHUP bool
+	}
+
+	RotateWriter struct {
+		cfg        FileConfig
+		mu         sync.Mutex
+		file       *os.File
+		size       int64
+		nextRotate time.Time
+		cleanMu    sync.Mutex
+		cleaning   sync.WaitGroup
+	}
+
+	backup struct {
+		path string
+		time time.Time
+	}
+)
+
+const (
+	backupTimeFormat = "20060102T150405.000"
+	compressSuffix   = ".gz"
+	megabyte         = 1024 * 1024
+)
+
+var (
+	ErrNoPath = errors.New("log file without path")
+)
+
+func NewRotateWriter(cfg FileConfig) (*RotateWriter, error) {
+	if len(cfg.Path) == 0 {
+		return nil, ErrNoPath
+	}
+
+	w := &RotateWriter{cfg: cfg}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, err
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.shouldRotate(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	return w.file.Sync()
+}
+
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	err := w.close()
+	w.mu.Unlock()
+
+	w.cleaning.Wait()
+	return err
+}
+
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.rotate()
+}
+
+// Reopen closes and reopens the file at the same path, for external tools that already moved it.
+func (w *RotateWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	return w.open()
+}
+
+// ReopenOnSignal reopens the file on every SIGHUP, the returned func stops listening.
+func (w *RotateWriter) ReopenOnSignal(onError func(error)) func() {
+	sig := make(chan os.Signal, 1)
+	done := make(chan bool)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	go func() {
+		for {
+			select {
+			case <-sig:
+				if err := w.Reopen(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sig)
+			close(done)
+		})
+	}
+}
+
+func (w *RotateWriter) open() error {
+	f, err := os.OpenFile(w.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+
+	w.file = f
+	w.size = fi.Size()
+	if w.cfg.Interval > 0 {
+		w.nextRotate = time.Now().Truncate(w.cfg.Interval).Add(w.cfg.Interval)
+	}
+
+	return nil
+}
+
+func (w *RotateWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotateWriter) shouldRotate(n int) bool {
+	if w.cfg.MaxSize > 0 && w.size > 0 && w.size+int64(n) > int64(w.cfg.MaxSize)*megabyte {
+		return true
+	}
+
+	return w.cfg.Interval > 0 && !time.Now().Before(w.nextRotate)
+}
+
+func (w *RotateWriter) rotate() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(w.cfg.Path); err == nil {
+		if err = os.Rename(w.cfg.Path, w.backupName()); err != nil {
+			return fmt.Errorf("rotate log file: %w", err)
+		}
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.cleaning.Add(1)
+	go w.cleanup()
+	return nil
+}
+
+// backupName never reuses an existing name, rotations within the same millisecond move to the next one.
+func (w *RotateWriter) backupName() string {
+	dir, name := filepath.Split(w.cfg.Path)
+	ext := filepath.Ext(name)
+
+	for t := time.Now(); ; t = t.Add(time.Millisecond) {
+		path := filepath.Join(dir, strings.TrimSuffix(name, ext)+"-"+t.Format(backupTimeFormat)+ext)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, err = os.Stat(path + compressSuffix); os.IsNotExist(err) {
+				return path
+			}
+		}
+	}
+}
+
+func (w *RotateWriter) backups() ([]backup, error) {
+	dir, name := filepath.Split(w.cfg.Path)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, f := range files {
+		fn := f.Name()
+		if f.IsDir() || !strings.HasPrefix(fn, prefix) {
+			continue
+		}
+
+		ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(fn, prefix), compressSuffix), ext)
+		t, err := time.ParseInLocation(backupTimeFormat, ts, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, fn), time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+
+	return backups, nil
+}
+
+func (w *RotateWriter) cleanup() {
+	defer w.cleaning.Done()
+
+	w.cleanMu.Lock()
+	defer w.cleanMu.Unlock()
+
+	backups, err := w.backups()
+	if err != nil {
+		return
+	}
+
+	for i, b := range backups {
+		expired := w.cfg.MaxAge > 0 && time.Since(b.time) > w.cfg.MaxAge
+		if (w.cfg.MaxBackups > 0 && i >= w.cfg.MaxBackups) || expired {
+			_ = os.Remove(b.path)
+			continue
+		}
+
+		if w.cfg.Compress && !strings.HasSuffix(b.path, compressSuffix) {
+			_ = compress(b.path)
+		}
+	}
+}
+
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
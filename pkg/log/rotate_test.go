@@ -0,0 +1,117 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotateWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        FileConfig
+		rotations  int
+		wantFiles  int
+		wantSuffix string
+	}{
+		{name: "keep backups", cfg: FileConfig{}, rotations: 3, wantFiles: 4, wantSuffix: ".log"},
+		{name: "max backups", cfg: FileConfig{MaxBackups: 2}, rotations: 3, wantFiles: 3, wantSuffix: ".log"},
+		{name: "compress", cfg: FileConfig{MaxBackups: 1, Compress: true}, rotations: 2, wantFiles: 2, wantSuffix: ".gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "era-log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			tt.cfg.Path = filepath.Join(dir, "app.log")
+			w, err := NewRotateWriter(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < tt.rotations; i++ {
+				if _, err := w.Write([]byte("line\n")); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Rotate(); err != nil {
+					t.Fatal(err)
+				}
+				w.cleaning.Wait()
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			files, _ := ioutil.ReadDir(dir)
+			if len(files) != tt.wantFiles {
+				t.Fatalf("files = %d, want %d", len(files), tt.wantFiles)
+			}
+			for _, f := range files {
+				if f.Name() != "app.log" && !strings.HasSuffix(f.Name(), tt.wantSuffix) {
+					t.Errorf("backup %s, want suffix %s", f.Name(), tt.wantSuffix)
+				}
+			}
+		})
+	}
+}
+
+func TestRotateWriterMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := NewRotateWriter(FileConfig{Path: filepath.Join(dir, "app.log"), MaxSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := []byte(strings.Repeat("x", megabyte/2))
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = w.Close()
+
+	backups, _ := w.backups()
+	if len(backups) != 1 {
+		t.Errorf("backups = %d, want 1", len(backups))
+	}
+}
+
+func TestRotateWriterReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotateWriter(FileConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	_, _ = w.Write([]byte("before\n"))
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("after\n"))
+
+	b, _ := ioutil.ReadFile(path)
+	if string(b) != "after\n" {
+		t.Errorf("reopened file = %q", b)
+	}
+}
@@ -0,0 +1,137 @@
+package log
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type (
+	// SamplingRule keeps the First lines per Tick of every logger name and message it matches,
+	// then every Thereafter-th one. An empty Name or Message matches all, Name matches like level overrides.
+	SamplingRule struct {
+		Name       string
+		Message    string
+		First      int
+		Thereafter int
+		Tick       time.Duration
+	}
+
+	sampler struct {
+		rule     SamplingRule
+		counters [samplerSize]counter
+	}
+
+	counter struct {
+		resetAt int64
+		n       uint64
+	}
+
+	samplingCore struct {
+		zapcore.Core
+		samplers []*sampler
+	}
+)
+
+const (
+	ReasonSampled   = "sampled"
+	ReasonThreshold = "threshold"
+
+	samplerSize = 1024
+)
+
+// WithSampling drops lines matching the rules, the first matching rule wins and errors are never dropped.
+func WithSampling(rules ...SamplingRule) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newSamplingCore(core, rules)
+	})
+}
+
+// CountDropped reports a line the caller chose not to log, e.g. a command faster than its slow threshold.
+func CountDropped(logger, reason string) {
+	metricsLogDroppedCounter.WithLabelValues(logger, reason).Inc()
+}
+
+func newSamplingCore(core zapcore.Core, rules []SamplingRule) zapcore.Core {
+	if len(rules) == 0 {
+		return core
+	}
+
+	samplers := make([]*sampler, 0, len(rules))
+	for _, r := range rules {
+		if r.Tick <= 0 {
+			r.Tick = time.Second
+		}
+		samplers = append(samplers, &sampler{rule: r})
+	}
+
+	return &samplingCore{Core: core, samplers: samplers}
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), samplers: c.samplers}
+}
+
+func (c *samplingCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return ce
+	}
+
+	if entry.Level < zapcore.ErrorLevel {
+		for _, s := range c.samplers {
+			if !s.match(entry) {
+				continue
+			}
+
+			if !s.keep(entry) {
+				CountDropped(entry.LoggerName, ReasonSampled)
+				return ce
+			}
+			break
+		}
+	}
+
+	return c.Core.Check(entry, ce)
+}
+
+func (s *sampler) match(entry zapcore.Entry) bool {
+	if len(s.rule.Message) > 0 && s.rule.Message != entry.Message {
+		return false
+	}
+
+	return len(s.rule.Name) == 0 || strings.Contains("."+entry.LoggerName+".", "."+s.rule.Name+".")
+}
+
+func (s *sampler) keep(entry zapcore.Entry) bool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(entry.LoggerName))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(entry.Message))
+
+	n := s.counters[h.Sum32()%samplerSize].inc(entry.Time, s.rule.Tick)
+	if n <= uint64(s.rule.First) {
+		return true
+	}
+
+	return s.rule.Thereafter > 0 && (n-uint64(s.rule.First))%uint64(s.rule.Thereafter) == 0
+}
+
+func (c *counter) inc(t time.Time, tick time.Duration) uint64 {
+	now := t.UnixNano()
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&c.n, 1)
+	}
+
+	atomic.StoreUint64(&c.n, 1)
+	newResetAt := now + tick.Nanoseconds()
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAt, newResetAt) {
+		return atomic.AddUint64(&c.n, 1)
+	}
+
+	return 1
+}
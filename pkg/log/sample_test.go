@@ -0,0 +1,59 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampling(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []SamplingRule
+		logger string
+		msg    string
+		level  zapcore.Level
+		want   int
+	}{
+		{name: "no rule", logger: "redis", msg: "redis: get", level: zapcore.InfoLevel, want: 10},
+		{name: "first then every 3rd", rules: []SamplingRule{{Name: "redis", First: 2, Thereafter: 3}}, logger: "redis", msg: "redis: get", level: zapcore.InfoLevel, want: 4},
+		{name: "first only", rules: []SamplingRule{{Name: "redis", First: 2}}, logger: "redis", msg: "redis: get", level: zapcore.InfoLevel, want: 2},
+		{name: "other logger", rules: []SamplingRule{{Name: "mysql", First: 1}}, logger: "redis", msg: "redis: get", level: zapcore.InfoLevel, want: 10},
+		{name: "other message", rules: []SamplingRule{{Message: "redis: set", First: 1}}, logger: "redis", msg: "redis: get", level: zapcore.InfoLevel, want: 10},
+		{name: "errors kept", rules: []SamplingRule{{First: 1}}, logger: "redis", msg: "redis: get", level: zapcore.ErrorLevel, want: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			l := zap.New(core, WithSampling(tt.rules...)).Named("era").Named(tt.logger)
+
+			for i := 0; i < 10; i++ {
+				if ce := l.Check(tt.level, tt.msg); ce != nil {
+					ce.Write()
+				}
+			}
+
+			if logs.Len() != tt.want {
+				t.Errorf("logged %d lines, want %d", logs.Len(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplingTick(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(core, WithSampling(SamplingRule{First: 1, Tick: 20 * time.Millisecond}))
+
+	l.Info("hot")
+	l.Info("hot")
+	time.Sleep(30 * time.Millisecond)
+	l.Info("hot")
+
+	if logs.Len() != 2 {
+		t.Errorf("logged %d lines, want 2", logs.Len())
+	}
+}
@@ -0,0 +1,199 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/uber/jaeger-client-go"
+)
+
+type (
+	TraceInfo struct {
+		TraceID string
+		SpanID  string
+		Sampled bool
+	}
+
+	// TraceExtractor reports the trace of ctx, ok is false when it doesn't understand the span in ctx.
+	TraceExtractor func(ctx context.Context) (info TraceInfo, ok bool)
+
+	namedExtractor struct {
+		name string
+		fn   TraceExtractor
+	}
+
+	// injected holds the headers of the span, injected once for all the extractors of an ExtractTrace.
+	injected struct {
+		done   bool
+		header http.Header
+		ok     bool
+	}
+
+	injectedKey struct{}
+)
+
+const (
+	ExtractorJaeger = "jaeger"
+	ExtractorMock   = "mock"
+	ExtractorW3C    = "w3c"
+	ExtractorB3     = "b3"
+
+	headerTraceParent = "traceparent"
+	headerB3          = "b3"
+	headerB3TraceID   = "X-B3-Traceid"
+	headerB3SpanID    = "X-B3-Spanid"
+	headerB3Sampled   = "X-B3-Sampled"
+	headerB3Flags     = "X-B3-Flags"
+)
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = []namedExtractor{
+		{name: ExtractorJaeger, fn: jaegerExtractor},
+		{name: ExtractorMock, fn: mockExtractor},
+		{name: ExtractorW3C, fn: w3cExtractor},
+		{name: ExtractorB3, fn: b3Extractor},
+	}
+)
+
+// RegisterTraceExtractor adds fn in front of the built-in extractors, or replaces the one with the same name.
+func RegisterTraceExtractor(name string, fn TraceExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	for i, e := range extractors {
+		if e.name == name {
+			extractors[i].fn = fn
+			return
+		}
+	}
+
+	extractors = append([]namedExtractor{{name: name, fn: fn}}, extractors...)
+}
+
+func ExtractTrace(ctx context.Context) (TraceInfo, bool) {
+	if ctx == nil {
+		return TraceInfo{}, false
+	}
+
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	ctx = context.WithValue(ctx, injectedKey{}, &injected{})
+	for _, e := range extractors {
+		if info, ok := e.fn(ctx); ok && len(info.TraceID) > 0 {
+			return info, true
+		}
+	}
+
+	return TraceInfo{}, false
+}
+
+func jaegerExtractor(ctx context.Context) (TraceInfo, bool) {
+	sp := opentracing.SpanFromContext(ctx)
+	if sp == nil {
+		return TraceInfo{}, false
+	}
+
+	sc, ok := sp.Context().(jaeger.SpanContext)
+	if !ok || !sc.IsValid() {
+		return TraceInfo{}, false
+	}
+
+	return TraceInfo{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Sampled: sc.IsSampled()}, true
+}
+
+func mockExtractor(ctx context.Context) (TraceInfo, bool) {
+	sp := opentracing.SpanFromContext(ctx)
+	if sp == nil {
+		return TraceInfo{}, false
+	}
+
+	sc, ok := sp.Context().(mocktracer.MockSpanContext)
+	if !ok {
+		return TraceInfo{}, false
+	}
+
+	return TraceInfo{TraceID: strconv.Itoa(sc.TraceID), SpanID: strconv.Itoa(sc.SpanID), Sampled: sc.Sampled}, true
+}
+
+// w3cExtractor covers every tracer propagating traceparent, e.g. the OpenTelemetry bridge.
+func w3cExtractor(ctx context.Context) (TraceInfo, bool) {
+	header, ok := injectHeader(ctx)
+	if !ok {
+		return TraceInfo{}, false
+	}
+
+	parts := strings.Split(header.Get(headerTraceParent), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return TraceInfo{}, false
+	}
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return TraceInfo{}, false
+	}
+
+	return TraceInfo{TraceID: parts[1], SpanID: parts[2], Sampled: flags&1 == 1}, true
+}
+
+func b3Extractor(ctx context.Context) (TraceInfo, bool) {
+	header, ok := injectHeader(ctx)
+	if !ok {
+		return TraceInfo{}, false
+	}
+
+	if single := header.Get(headerB3); len(single) > 0 {
+		parts := strings.Split(single, "-")
+		if len(parts) < 2 {
+			return TraceInfo{}, false
+		}
+
+		info := TraceInfo{TraceID: parts[0], SpanID: parts[1]}
+		if len(parts) > 2 {
+			info.Sampled = parts[2] == "1" || parts[2] == "d"
+		}
+		return info, true
+	}
+
+	info := TraceInfo{
+		TraceID: header.Get(headerB3TraceID),
+		SpanID:  header.Get(headerB3SpanID),
+		Sampled: header.Get(headerB3Sampled) == "1" || header.Get(headerB3Sampled) == "true" || header.Get(headerB3Flags) == "1",
+	}
+
+	return info, len(info.TraceID) > 0
+}
+
+// injectHeader is the span of ctx injected as HTTP headers, at most once per ExtractTrace.
+func injectHeader(ctx context.Context) (http.Header, bool) {
+	in, ok := ctx.Value(injectedKey{}).(*injected)
+	if !ok {
+		return inject(ctx)
+	}
+
+	if !in.done {
+		in.header, in.ok = inject(ctx)
+		in.done = true
+	}
+	return in.header, in.ok
+}
+
+func inject(ctx context.Context) (http.Header, bool) {
+	sp := opentracing.SpanFromContext(ctx)
+	if sp == nil {
+		return nil, false
+	}
+
+	header := make(http.Header)
+	if err := sp.Tracer().Inject(sp.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)); err != nil {
+		return nil, false
+	}
+
+	return header, true
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/uber/jaeger-client-go"
+)
+
+type (
+	headerTracer struct {
+		opentracing.NoopTracer
+		header  http.Header
+		injects int
+	}
+
+	headerSpan struct {
+		opentracing.Span
+		tracer *headerTracer
+	}
+)
+
+func (t *headerTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	t.injects++
+	w := carrier.(opentracing.TextMapWriter)
+	for k := range t.header {
+		w.Set(k, t.header.Get(k))
+	}
+	return nil
+}
+
+func (s headerSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+func headerContext(kv ...string) context.Context {
+	t := &headerTracer{header: make(http.Header)}
+	for i := 0; i < len(kv); i += 2 {
+		t.header.Set(kv[i], kv[i+1])
+	}
+
+	return opentracing.ContextWithSpan(context.Background(), headerSpan{Span: t.NoopTracer.StartSpan("op"), tracer: t})
+}
+
+func TestExtractTrace(t *testing.T) {
+	jaegerTracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	defer closer.Close()
+	jaegerSpan := jaegerTracer.StartSpan("op")
+	jaegerCtx := jaegerSpan.Context().(jaeger.SpanContext)
+
+	mockSpan := mocktracer.New().StartSpan("op")
+	mockCtx := mockSpan.Context().(mocktracer.MockSpanContext)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		want   TraceInfo
+		wantOK bool
+	}{
+		{
+			name:   "no span",
+			ctx:    context.Background(),
+			wantOK: false,
+		},
+		{
+			name:   "jaeger",
+			ctx:    opentracing.ContextWithSpan(context.Background(), jaegerSpan),
+			want:   TraceInfo{TraceID: jaegerCtx.TraceID().String(), SpanID: jaegerCtx.SpanID().String(), Sampled: true},
+			wantOK: true,
+		},
+		{
+			name:   "mock",
+			ctx:    opentracing.ContextWithSpan(context.Background(), mockSpan),
+			want:   TraceInfo{TraceID: strconv.Itoa(mockCtx.TraceID), SpanID: strconv.Itoa(mockCtx.SpanID), Sampled: true},
+			wantOK: true,
+		},
+		{
+			name:   "w3c",
+			ctx:    headerContext("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"),
+			want:   TraceInfo{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+			wantOK: true,
+		},
+		{
+			name:   "b3 multi",
+			ctx:    headerContext("X-B3-TraceId", "463ac35c9f6413ad", "X-B3-SpanId", "a2fb4a1d1a96d312", "X-B3-Sampled", "0"),
+			want:   TraceInfo{TraceID: "463ac35c9f6413ad", SpanID: "a2fb4a1d1a96d312"},
+			wantOK: true,
+		},
+		{
+			name:   "b3 single",
+			ctx:    headerContext("b3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"),
+			want:   TraceInfo{TraceID: "80f198ee56343ba864fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1", Sampled: true},
+			wantOK: true,
+		},
+		{
+			name:   "noop",
+			ctx:    opentracing.ContextWithSpan(context.Background(), opentracing.NoopTracer{}.StartSpan("op")),
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractTrace(tt.ctx)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ExtractTrace() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestExtractTraceInjectsOnce(t *testing.T) {
+	tracer := &headerTracer{header: http.Header{"Uber-Trace-Id": []string{"unknown"}}}
+	ctx := opentracing.ContextWithSpan(context.Background(), headerSpan{Span: tracer.NoopTracer.StartSpan("op"), tracer: tracer})
+
+	if _, ok := ExtractTrace(ctx); ok || tracer.injects != 1 {
+		t.Errorf("ExtractTrace() ok = %v after %d injects", ok, tracer.injects)
+	}
+}
+
+func TestRegisterTraceExtractor(t *testing.T) {
+	RegisterTraceExtractor("custom", func(ctx context.Context) (TraceInfo, bool) {
+		return TraceInfo{TraceID: "custom"}, true
+	})
+	defer func() {
+		extractorsMu.Lock()
+		extractors = extractors[1:]
+		extractorsMu.Unlock()
+	}()
+
+	if got, ok := ExtractTrace(context.Background()); !ok || got.TraceID != "custom" {
+		t.Fatalf("ExtractTrace() = %+v, %v", got, ok)
+	}
+}
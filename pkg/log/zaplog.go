@@ -2,21 +2,57 @@ package log
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 
-	"github.com/opentracing/opentracing-go"
-	"github.com/uber/jaeger-client-go"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/GaVender/era/config"
+	"github.com/GaVender/era/pkg/errs"
 )
 
-type ZapLogger struct {
-	*zap.Logger
-}
+type (
+	// Config selects the outputs of the logger, stdout is used when neither output is set.
+	Config struct {
+		Level    string
+		Levels   map[string]string
+		Sampling []SamplingRule
+		Prd      bool
+		Stdout   bool
+		File     FileConfig
+	}
+
+	ZapLogger struct {
+		*zap.Logger
+		levels *Levels
+	}
+
+	// Error is returned with Kind ErrInit or ErrSync.
+	Error = errs.Error
+)
+
+var (
+	ErrInit = errors.New("zap logger init")
+	ErrSync = errors.New("zap logger sync")
+)
 
+// NewZapLogger panics when the logger can't be built, its closer writes a close error to stderr.
 func NewZapLogger(project string, isPrd bool, opt ...zap.Option) (*ZapLogger, func()) {
+	z, closer, err := NewZapLoggerE(project, isPrd, opt...)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return z, func() {
+		if err := closer(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+	}
+}
+
+func NewZapLoggerE(project string, isPrd bool, opt ...zap.Option) (*ZapLogger, func() error, error) {
 	var (
 		c   zap.Config
 		l   *zap.Logger
@@ -30,89 +66,231 @@ func NewZapLogger(project string, isPrd bool, opt ...zap.Option) (*ZapLogger, fu
 		c = zap.NewDevelopmentConfig()
 	}
 
+	levels := NewLevels(c.Level)
+	c.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	opt = append(opt, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newLevelCore(core, levels)
+	}))
+
 	l, err = c.Build(opt...)
 	if err != nil {
-		panic("zap logger init: " + err.Error())
+		return nil, nil, &Error{Kind: ErrInit, Err: err}
 	}
 
 	l = l.Named(project).WithOptions(zap.AddCallerSkip(1))
-	return &ZapLogger{l}, func() {
-		if err = l.Sync(); err != nil {
-			panic("zap logger sync: " + err.Error())
+	return &ZapLogger{Logger: l, levels: levels}, func() error {
+		if err := l.Sync(); err != nil {
+			return &Error{Kind: ErrSync, Err: err}
+		}
+		return nil
+	}, nil
+}
+
+func NewZapLoggerWithConfig(project string, cfg Config, opt ...zap.Option) (*ZapLogger, func() error, error) {
+	var (
+		encoderCfg zapcore.EncoderConfig
+		level      = zap.NewAtomicLevel()
+		sinks      []zapcore.WriteSyncer
+		stopHUP    = func() {}
+		file       *RotateWriter
+		err        error
+	)
+
+	if cfg.Prd {
+		encoderCfg = zap.NewProductionEncoderConfig()
+		encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		opt = append([]zap.Option{zap.AddStacktrace(zapcore.ErrorLevel)}, opt...)
+	} else {
+		encoderCfg = zap.NewDevelopmentEncoderConfig()
+		level.SetLevel(zapcore.DebugLevel)
+		opt = append([]zap.Option{zap.Development(), zap.AddStacktrace(zapcore.WarnLevel)}, opt...)
+	}
+
+	if len(cfg.Level) > 0 {
+		if err = level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, nil, &Error{Kind: ErrInit, Err: err}
+		}
+	}
+
+	levels := NewLevels(level)
+	for name, lvl := range cfg.Levels {
+		if err = levels.SetLevel(name, lvl, 0); err != nil {
+			return nil, nil, &Error{Kind: ErrInit, Err: err}
 		}
 	}
+
+	if len(cfg.File.Path) > 0 {
+		if file, err = NewRotateWriter(cfg.File); err != nil {
+			return nil, nil, &Error{Kind: ErrInit, Err: err}
+		}
+		sinks = append(sinks, file)
+	}
+
+	if cfg.Stdout || len(sinks) == 0 {
+		sinks = append(sinks, zapcore.Lock(os.Stdout))
+	}
+
+	var encoder zapcore.Encoder
+	if cfg.Prd {
+		encoder = zapcore.NewJSONEncoder(encoderCfg)
+	} else {
+		encoder = zapcore.NewConsoleEncoder(encoderCfg)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(sinks...), zapcore.DebugLevel)
+	core = newLevelCore(newSamplingCore(core, cfg.Sampling), levels)
+	opt = append([]zap.Option{zap.AddCaller(), zap.ErrorOutput(zapcore.Lock(os.Stderr))}, opt...)
+	l := zap.New(core, opt...).Named(project).WithOptions(zap.AddCallerSkip(1))
+
+	if file != nil && cfg.File.ReopenOnHUP {
+		stopHUP = file.ReopenOnSignal(func(err error) {
+			l.Error("reopen log file: " + err.Error())
+		})
+	}
+
+	return &ZapLogger{Logger: l, levels: levels}, func() error {
+		stopHUP()
+		if file == nil {
+			return nil
+		}
+
+		if err := file.Close(); err != nil {
+			return &Error{Kind: ErrSync, Err: err}
+		}
+		return nil
+	}, nil
 }
 
+// WithContext returns a child logger carrying the trace id of ctx, z itself is left untouched.
 func (z *ZapLogger) WithContext(ctx context.Context) *ZapLogger {
-	z.Logger = z.Logger.With(zap.String(config.TraceID, z.gerTraceID(ctx)))
-	return z
+	return &ZapLogger{Logger: z.Logger.With(z.traceFields(ctx)...), levels: z.levels}
 }
 
-func (z *ZapLogger) gerTraceID(ctx context.Context) string {
-	var traceID string
+func (z *ZapLogger) SetLevel(level string) error {
+	return z.levels.SetLevel("", level, 0)
+}
 
-	sp := opentracing.SpanFromContext(ctx)
-	if sp != nil {
-		if jaegerSpanContext, ok := sp.Context().(jaeger.SpanContext); ok {
-			traceID = jaegerSpanContext.TraceID().String()
-		}
+func (z *ZapLogger) Level() zap.AtomicLevel {
+	return z.levels.Base()
+}
+
+// Levels controls the base and the per-named-logger levels, it is also the HTTP handler to mount.
+func (z *ZapLogger) Levels() *Levels {
+	return z.levels
+}
+
+func (z *ZapLogger) traceFields(ctx context.Context) []zap.Field {
+	info, ok := ExtractTrace(ctx)
+	if !ok {
+		return nil
 	}
 
-	return traceID
+	return []zap.Field{
+		zap.String(config.TraceID, info.TraceID),
+		zap.String(config.SpanID, info.SpanID),
+		zap.Bool(config.Sampled, info.Sampled),
+	}
 }
 
 func (z *ZapLogger) Error(msg string) {
-	z.With().Error(msg)
+	z.Logger.Error(msg)
 }
 
 func (z *ZapLogger) Print(v ...interface{}) {
-	z.With().Info(fmt.Sprint(v...))
+	z.Logger.Info(fmt.Sprint(v...))
 }
 
 func (z *ZapLogger) Debugf(format string, v ...interface{}) {
-	z.With().Debug(fmt.Sprintf(format, v...))
+	z.Logger.Debug(fmt.Sprintf(format, v...))
 }
 
 func (z *ZapLogger) Infof(format string, v ...interface{}) {
-	z.With().Info(fmt.Sprintf(format, v...))
+	z.Logger.Info(fmt.Sprintf(format, v...))
+}
+
+func (z *ZapLogger) Warnf(format string, v ...interface{}) {
+	z.Logger.Warn(fmt.Sprintf(format, v...))
 }
 
 func (z *ZapLogger) Errorf(format string, v ...interface{}) {
-	z.With().Error(fmt.Sprintf(format, v...))
+	z.Logger.Error(fmt.Sprintf(format, v...))
 }
 
 func (z *ZapLogger) Panicf(format string, v ...interface{}) {
-	z.With().Panic(fmt.Sprintf(format, v...))
+	z.Logger.Panic(fmt.Sprintf(format, v...))
 }
 
 func (z *ZapLogger) ContextDebugf(ctx context.Context, format string, v ...interface{}) {
-	z.With(zap.String(config.TraceID, z.gerTraceID(ctx))).Debug(fmt.Sprintf(format, v...))
+	z.Logger.With(z.traceFields(ctx)...).Debug(fmt.Sprintf(format, v...))
 }
 
 func (z *ZapLogger) ContextInfof(ctx context.Context, format string, v ...interface{}) {
-	z.With(zap.String(config.TraceID, z.gerTraceID(ctx))).Info(fmt.Sprintf(format, v...))
+	z.Logger.With(z.traceFields(ctx)...).Info(fmt.Sprintf(format, v...))
+}
+
+func (z *ZapLogger) ContextWarnf(ctx context.Context, format string, v ...interface{}) {
+	z.Logger.With(z.traceFields(ctx)...).Warn(fmt.Sprintf(format, v...))
 }
 
 func (z *ZapLogger) ContextErrorf(ctx context.Context, format string, v ...interface{}) {
-	z.With(zap.String(config.TraceID, z.gerTraceID(ctx))).Error(fmt.Sprintf(format, v...))
+	z.Logger.With(z.traceFields(ctx)...).Error(fmt.Sprintf(format, v...))
 }
 
 func (z *ZapLogger) ContextPanicf(ctx context.Context, format string, v ...interface{}) {
-	z.With(zap.String(config.TraceID, z.gerTraceID(ctx))).Panic(fmt.Sprintf(format, v...))
+	z.Logger.With(z.traceFields(ctx)...).Panic(fmt.Sprintf(format, v...))
+}
+
+func (z *ZapLogger) DebugField(msg string, fields ...Field) {
+	z.Logger.Debug(msg, zapFields(fields)...)
+}
+
+func (z *ZapLogger) InfoField(msg string, fields ...Field) {
+	z.Logger.Info(msg, zapFields(fields)...)
+}
+
+func (z *ZapLogger) WarnField(msg string, fields ...Field) {
+	z.Logger.Warn(msg, zapFields(fields)...)
+}
+
+func (z *ZapLogger) ErrorField(msg string, fields ...Field) {
+	z.Logger.Error(msg, zapFields(fields)...)
+}
+
+func (z *ZapLogger) PanicField(msg string, fields ...Field) {
+	z.Logger.Panic(msg, zapFields(fields)...)
 }
 
-func (z *ZapLogger) DebugField(msg string, fields ...zap.Field) {
-	z.Logger.Debug(msg, fields...)
+func (z *ZapLogger) ContextDebugField(ctx context.Context, msg string, fields ...Field) {
+	z.Logger.Debug(msg, zapFields(fields, z.traceFields(ctx)...)...)
 }
 
-func (z *ZapLogger) InfoField(msg string, fields ...zap.Field) {
-	z.Logger.Info(msg, fields...)
+func (z *ZapLogger) ContextInfoField(ctx context.Context, msg string, fields ...Field) {
+	z.Logger.Info(msg, zapFields(fields, z.traceFields(ctx)...)...)
 }
 
-func (z *ZapLogger) ErrorField(msg string, fields ...zap.Field) {
-	z.Logger.Error(msg, fields...)
+func (z *ZapLogger) ContextWarnField(ctx context.Context, msg string, fields ...Field) {
+	z.Logger.Warn(msg, zapFields(fields, z.traceFields(ctx)...)...)
 }
 
-func (z *ZapLogger) PanicField(msg string, fields ...zap.Field) {
-	z.Logger.Panic(msg, fields...)
+func (z *ZapLogger) ContextErrorField(ctx context.Context, msg string, fields ...Field) {
+	z.Logger.Error(msg, zapFields(fields, z.traceFields(ctx)...)...)
 }
+
+func (z *ZapLogger) With(fields ...Field) Logger {
+	return &ZapLogger{Logger: z.Logger.With(zapFields(fields)...), levels: z.levels}
+}
+
+func (z *ZapLogger) Named(name string) Logger {
+	return &ZapLogger{Logger: z.Logger.Named(name), levels: z.levels}
+}
+
+func zapFields(fields []Field, extra ...zap.Field) []zap.Field {
+	zf := make([]zap.Field, 0, len(fields)+len(extra))
+	for _, f := range fields {
+		zf = append(zf, zap.Any(f.Key, f.Value))
+	}
+
+	return append(zf, extra...)
+}
+
+var _ Logger = &ZapLogger{}
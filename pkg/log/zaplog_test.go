@@ -0,0 +1,60 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/GaVender/era/config"
+)
+
+func TestZapLoggerChildren(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	z := &ZapLogger{Logger: zap.New(core), levels: NewLevels(zap.NewAtomicLevel())}
+
+	tracer := mocktracer.New()
+	ctx := opentracing.ContextWithSpan(context.Background(), tracer.StartSpan("a"))
+
+	z.WithContext(ctx).Infof("first")
+	z.WithContext(ctx).Infof("second")
+	z.With(String("request_id", "1")).InfoField("third", Int("status", 200))
+	z.Infof("parent")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries", len(entries))
+	}
+
+	for i, want := range []int{3, 3, 2, 0} {
+		if got := len(entries[i].Context); got != want {
+			t.Errorf("entry %q has %d fields, want %d: %v", entries[i].Message, got, want, entries[i].Context)
+		}
+	}
+
+	if entries[0].Context[0].Key != config.TraceID || entries[0].Context[1].Key != config.SpanID {
+		t.Errorf("missing trace id: %v", entries[0].Context)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	z := &ZapLogger{Logger: zap.New(core), levels: NewLevels(zap.NewAtomicLevel())}
+
+	if _, ok := FromContext(context.Background()).(NullLogger); !ok {
+		t.Fatal("FromContext() without logger should be NullLogger")
+	}
+
+	fallback := NullLogger{}
+	ctx := IntoContext(context.Background(), z.With(String("request_id", "1")))
+	FromContextOr(ctx, fallback).ContextInfoField(ctx, "hook")
+
+	entries := logs.FilterField(zap.String("request_id", "1")).All()
+	if len(entries) != 1 || entries[0].Message != "hook" {
+		t.Fatalf("request-scoped logger not used: %v", logs.All())
+	}
+}
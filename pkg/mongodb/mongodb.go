@@ -2,17 +2,24 @@ package mongodb
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 
+	"github.com/GaVender/era/pkg/backoff"
+	"github.com/GaVender/era/pkg/errs"
 	"github.com/GaVender/era/pkg/log"
+	"github.com/GaVender/era/pkg/opentrace"
+	"github.com/GaVender/era/pkg/redact"
 )
 
 type (
@@ -30,13 +37,21 @@ type (
 		tracer          opentracing.Tracer
 		ableMonitor     bool
 		monitorInterval time.Duration
+		retry           backoff.Policy
+		slowThreshold   time.Duration
+		redactor        *redact.Redactor
 		close           chan bool
 	}
 
+	// Error is returned with Kind ErrConfig, ErrConnect or ErrClose.
+	Error = errs.Error
+
 	hook struct {
-		tracer      opentracing.Tracer
-		logger      log.Logger
-		ableMonitor bool
+		tracer        opentracing.Tracer
+		logger        log.Logger
+		ableMonitor   bool
+		slowThreshold time.Duration
+		redactor      *redact.Redactor
 	}
 
 	Option func(*Mongo)
@@ -47,11 +62,29 @@ const (
 )
 
 var (
+	ErrConfig  = errors.New("mongodb config")
+	ErrConnect = errors.New("mongodb connect")
+	ErrClose   = errors.New("mongodb close")
+
 	startTime  sync.Map
 	startEvent sync.Map
 )
 
+// NewConnection panics when the client can't connect, its closer logs a close error.
 func NewConnection(cfg Config, opts ...Option) (Mongo, func()) {
+	m, closer, err := NewConnectionE(cfg, opts...)
+	if err != nil {
+		panic("mongodb init: " + err.Error())
+	}
+
+	return m, func() {
+		if err := closer(); err != nil {
+			m.logger.Errorf(err.Error())
+		}
+	}
+}
+
+func NewConnectionE(cfg Config, opts ...Option) (Mongo, func() error, error) {
 	m := Mongo{
 		close: make(chan bool),
 	}
@@ -60,11 +93,17 @@ func NewConnection(cfg Config, opts ...Option) (Mongo, func()) {
 		opt(&m)
 	}
 
+	if m.logger == nil {
+		m.logger = log.NullLogger{}
+	}
+
 	idleTime := time.Duration(cfg.MaxConnIdleTime)
 	h := hook{
-		tracer:      m.tracer,
-		logger:      m.logger,
-		ableMonitor: m.ableMonitor,
+		tracer:        m.tracer,
+		logger:        m.logger,
+		ableMonitor:   m.ableMonitor,
+		slowThreshold: m.slowThreshold,
+		redactor:      m.redactor,
 	}
 
 	client, err := mongo.Connect(
@@ -86,21 +125,33 @@ func NewConnection(cfg Config, opts ...Option) (Mongo, func()) {
 		}),
 	)
 	if err != nil {
-		panic("mongodb init: " + err.Error())
+		return Mongo{}, nil, &Error{Kind: ErrConfig, Err: err}
 	}
 
 	ctx := context.Background()
-	if err = client.Ping(ctx, readpref.Primary()); err != nil {
-		panic("mongodb ping: " + err.Error())
+	err = backoff.Retry(ctx, m.retry, func(int) error {
+		return client.Ping(ctx, readpref.Primary())
+	}, func(attempt int, err error, wait time.Duration) {
+		m.logger.Errorf("mongodb connect attempt %d: %s, retry in %s", attempt, err.Error(), wait)
+	})
+	if err != nil {
+		_ = client.Disconnect(ctx)
+		return Mongo{}, nil, &Error{Kind: ErrConnect, Err: err}
 	}
 
 	m.Client = client
-	return m, func() {
-		if err = client.Disconnect(ctx); err != nil {
+
+	var once sync.Once
+	return m, func() error {
+		once.Do(func() {
 			close(m.close)
-			m.logger.Errorf("mongodb close: %s", err.Error())
+		})
+
+		if err := client.Disconnect(ctx); err != nil {
+			return &Error{Kind: ErrClose, Err: err}
 		}
-	}
+		return nil
+	}, nil
 }
 
 func WithLogger(logger log.Logger) Option {
@@ -115,6 +166,27 @@ func WithTracer(tracer opentracing.Tracer) Option {
 	}
 }
 
+// WithSlowThreshold only logs the commands slower than threshold, failed commands are always logged.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(m *Mongo) {
+		m.slowThreshold = threshold
+	}
+}
+
+// WithRedactor masks the logged and traced commands with redactor instead of redact.Default().
+func WithRedactor(redactor *redact.Redactor) Option {
+	return func(m *Mongo) {
+		m.redactor = redactor
+	}
+}
+
+// WithRetry retries the initial ping, by default the connection fails on the first error.
+func WithRetry(policy backoff.Policy) Option {
+	return func(m *Mongo) {
+		m.retry = policy
+	}
+}
+
 func WithMonitor(able bool, interval time.Duration) Option {
 	return func(m *Mongo) {
 		m.ableMonitor = able
@@ -148,27 +220,16 @@ func (h hook) succeed() func(context.Context, *event.CommandSucceededEvent) {
 		operationInfo := operation + succeededEvent.CommandName
 
 		if h.tracer != nil {
-			var childSp opentracing.Span
-			sp := opentracing.SpanFromContext(ctx)
-			if sp == nil {
-				childSp, ctx = opentracing.StartSpanFromContextWithTracer(
-					ctx,
-					h.tracer,
-					operationInfo,
-					opentracing.StartTime(startedTime.(time.Time)),
-				)
-			} else {
-				childSp = h.tracer.StartSpan(
-					operationInfo,
-					opentracing.ChildOf(sp.Context()),
-					opentracing.StartTime(startedTime.(time.Time)),
-				)
-			}
-
-			childSp.SetTag("mongodb request id", succeededEvent.RequestID).
-				SetTag("command", startedEvent.Command).
-				SetTag("result", succeededEvent.Reply.String())
-			childSp.Finish()
+			var sp opentracing.Span
+			sp, ctx = opentrace.StartChild(ctx, h.tracer, operationInfo,
+				opentracing.StartTime(startedTime.(time.Time)), h.tags(startedEvent))
+
+			command := h.redact(startedEvent.Command)
+			sp.SetTag("mongodb request id", succeededEvent.RequestID).
+				SetTag("command", command).
+				SetTag("result", h.redact(succeededEvent.Reply))
+			h.tag(sp, startedEvent, succeededEvent.CommandName, command)
+			opentrace.Finish(sp, nil)
 		}
 
 		if h.ableMonitor {
@@ -177,8 +238,16 @@ func (h hook) succeed() func(context.Context, *event.CommandSucceededEvent) {
 				Observe(float64(time.Duration(succeededEvent.DurationNanos).Milliseconds()))
 		}
 
-		h.logger.ContextInfof(ctx, fmt.Sprint(operation, "command: ", startedEvent.Command,
-			" , duration: ", time.Duration(succeededEvent.DurationNanos).Milliseconds()))
+		if duration := time.Duration(succeededEvent.DurationNanos); duration < h.slowThreshold {
+			log.CountDropped("mongodb", log.ReasonThreshold)
+		} else {
+			log.FromContextOr(ctx, h.logger).Named("mongodb").ContextInfoField(ctx, operationInfo,
+				log.String("db", startedEvent.DatabaseName),
+				log.String("command", succeededEvent.CommandName),
+				log.String("statement", h.redact(startedEvent.Command)),
+				log.Duration("duration", duration),
+			)
+		}
 		startTime.Delete(succeededEvent.RequestID)
 		startEvent.Delete(succeededEvent.RequestID)
 	}
@@ -203,27 +272,15 @@ func (h hook) fail() func(context.Context, *event.CommandFailedEvent) {
 		operationInfo := operation + failedEvent.CommandName
 
 		if h.tracer != nil {
-			var childSp opentracing.Span
-			sp := opentracing.SpanFromContext(ctx)
-			if sp == nil {
-				childSp, ctx = opentracing.StartSpanFromContextWithTracer(
-					ctx,
-					h.tracer,
-					operationInfo,
-					opentracing.StartTime(startedTime.(time.Time)),
-				)
-			} else {
-				childSp = h.tracer.StartSpan(
-					operationInfo,
-					opentracing.ChildOf(sp.Context()),
-					opentracing.StartTime(startedTime.(time.Time)),
-				)
-			}
-
-			childSp.SetTag("mongodb request id", failedEvent.RequestID).
-				SetTag("command", startedEvent.Command).
-				SetTag("error", failedEvent.Failure)
-			childSp.Finish()
+			var sp opentracing.Span
+			sp, ctx = opentrace.StartChild(ctx, h.tracer, operationInfo,
+				opentracing.StartTime(startedTime.(time.Time)), h.tags(startedEvent))
+
+			command := h.redact(startedEvent.Command)
+			sp.SetTag("mongodb request id", failedEvent.RequestID).
+				SetTag("command", command)
+			h.tag(sp, startedEvent, failedEvent.CommandName, command)
+			opentrace.Finish(sp, errors.New(failedEvent.Failure))
 		}
 
 		if h.ableMonitor {
@@ -232,7 +289,13 @@ func (h hook) fail() func(context.Context, *event.CommandFailedEvent) {
 				Observe(float64(time.Duration(failedEvent.DurationNanos).Milliseconds()))
 		}
 
-		h.logger.ContextErrorf(ctx, fmt.Sprint(operationInfo, " , duration: ", time.Duration(failedEvent.DurationNanos).Milliseconds()))
+		log.FromContextOr(ctx, h.logger).Named("mongodb").ContextErrorField(ctx, operationInfo,
+			log.String("db", startedEvent.DatabaseName),
+			log.String("command", failedEvent.CommandName),
+			log.String("statement", h.redact(startedEvent.Command)),
+			log.Duration("duration", time.Duration(failedEvent.DurationNanos)),
+			log.String("error", failedEvent.Failure),
+		)
 		startTime.Delete(failedEvent.RequestID)
 		startEvent.Delete(failedEvent.RequestID)
 	}
@@ -245,3 +308,28 @@ func (h hook) poolMonitor() func(poolEvent *event.PoolEvent) {
 		}
 	}
 }
+
+// tags are the start tags of the span, the connection id looks like localhost:27017[-5].
+func (h hook) tags(started *event.CommandStartedEvent) opentracing.Tags {
+	addr := started.ConnectionID
+	if i := strings.IndexByte(addr, '['); i >= 0 {
+		addr = addr[:i]
+	}
+
+	return opentrace.DBTags("mongodb", started.DatabaseName, addr)
+}
+
+func (h hook) tag(sp opentracing.Span, started *event.CommandStartedEvent, command, statement string) {
+	opentrace.SetAttributes(sp,
+		semconv.DBOperation(command),
+		semconv.DBStatement(statement),
+	)
+
+	if collection, ok := started.Command.Lookup(command).StringValueOK(); ok {
+		opentrace.SetAttributes(sp, semconv.DBMongoDBCollection(collection))
+	}
+}
+
+func (h hook) redact(doc bson.Raw) string {
+	return redact.Or(h.redactor).JSON([]byte(doc.String()))
+}
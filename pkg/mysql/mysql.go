@@ -2,15 +2,24 @@ package mysql
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	driver "github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	"github.com/opentracing/opentracing-go"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 
+	"github.com/GaVender/era/pkg/backoff"
+	"github.com/GaVender/era/pkg/breaker"
+	"github.com/GaVender/era/pkg/errs"
 	"github.com/GaVender/era/pkg/log"
+	"github.com/GaVender/era/pkg/opentrace"
+	"github.com/GaVender/era/pkg/redact"
 )
 
 type (
@@ -29,38 +38,58 @@ type (
 		db              string
 		ableMonitor     bool
 		monitorInterval time.Duration
+		retry           backoff.Policy
+		slowThreshold   time.Duration
+		redactor        *redact.Redactor
+		breaker         *breaker.Breaker
+		reload          chan time.Duration
 		close           chan bool
 	}
 
+	// Error is returned with Kind ErrConfig, ErrConnect or ErrClose.
+	Error = errs.Error
+
 	Option func(db *Client)
 )
 
 const (
-	keyBegin = "begin"
-	keyCtx   = "ctx"
+	keyBegin    = "begin"
+	keyCtx      = "ctx"
+	keyBreaker  = "breaker"
+	keyRejected = "rejected"
 
 	operation = "mysql: "
 )
 
-func NewClient(cfg Config, opts ...Option) (Client, func()) {
-	if len(cfg.DBName) == 0 {
-		panic("mysql config without db name")
-	}
+var (
+	ErrConfig  = errors.New("mysql config")
+	ErrConnect = errors.New("mysql connect")
+	ErrClose   = errors.New("mysql close")
+)
 
-	db, err := gorm.Open("mysql", cfg.Conn)
+// NewClient panics when the client can't connect, its closer logs a close error.
+func NewClient(cfg Config, opts ...Option) (Client, func()) {
+	client, closer, err := NewClientE(cfg, opts...)
 	if err != nil {
 		panic("mysql init: " + err.Error())
 	}
 
-	db.SingularTable(true)
-	db.BlockGlobalUpdate(true)
-	db.DB().SetConnMaxLifetime(time.Millisecond * time.Duration(cfg.MaxLifeTime))
-	db.DB().SetMaxIdleConns(cfg.MaxIdleConn)
-	db.DB().SetMaxOpenConns(cfg.MaxOpenConn)
+	return client, func() {
+		if err := closer(); err != nil {
+			client.logger.Errorf(err.Error())
+		}
+	}
+}
+
+func NewClientE(cfg Config, opts ...Option) (Client, func() error, error) {
+	if len(cfg.DBName) == 0 {
+		return Client{}, nil, &Error{Kind: ErrConfig, Err: errors.New("without db name")}
+	}
 
 	client := Client{
-		db:    cfg.DBName,
-		close: make(chan bool),
+		db:     cfg.DBName,
+		reload: make(chan time.Duration, 1),
+		close:  make(chan bool),
 	}
 
 	for _, opt := range opts {
@@ -70,58 +99,103 @@ func NewClient(cfg Config, opts ...Option) (Client, func()) {
 	if client.logger == nil {
 		client.logger = log.NullLogger{}
 	}
+
+	var db *gorm.DB
+	err := backoff.Retry(context.Background(), client.retry, func(int) error {
+		var err error
+		db, err = gorm.Open("mysql", cfg.Conn)
+		return err
+	}, func(attempt int, err error, wait time.Duration) {
+		client.logger.Errorf("mysql connect attempt %d: %s, retry in %s", attempt, err.Error(), wait)
+	})
+	if err != nil {
+		return Client{}, nil, &Error{Kind: ErrConnect, Err: err}
+	}
+
+	db.SingularTable(true)
+	db.BlockGlobalUpdate(true)
+	setPool(db, cfg)
 	db.SetLogger(client.logger)
 	client.DB = db
 
+	tags := opentrace.DBTags("mysql", cfg.DBName, addr(cfg.Conn))
 	scopeBegin := func(scope *gorm.Scope) {
 		scope.Set(keyBegin, time.Now())
+		if client.breaker == nil {
+			return
+		}
+
+		// Row() has no way to report the error, only Rows() goes through the breaker
+		result, isRowQuery := scope.InstanceGet("row_query_result")
+		rows, isRows := result.(*gorm.RowsQueryResult)
+		if isRowQuery && !isRows {
+			return
+		}
+
+		done, err := client.breaker.Allow()
+		if err != nil {
+			scope.Set(keyRejected, true)
+			scope.Err(err)
+			if isRows {
+				rows.Error = err
+				scope.SkipLeft()
+			}
+			return
+		}
+		scope.Set(keyBreaker, done)
 	}
 	scopeTrace := func(scope *gorm.Scope) {
-		beginTime, ok := scope.Get(keyBegin)
-		if !ok {
-			beginTime = time.Now()
+		if _, rejected := scope.Get(keyRejected); rejected {
+			return
 		}
 
-		var duration time.Duration
-		if bt, ok := beginTime.(time.Time); ok {
-			duration = time.Now().Sub(bt)
+		beginTime := time.Now()
+		if bt, ok := scope.Get(keyBegin); ok {
+			if t, ok := bt.(time.Time); ok {
+				beginTime = t
+			}
 		}
+		duration := time.Now().Sub(beginTime)
 
 		ctx := context.Background()
-		operationInfo := fmt.Sprint(operation, strings.ToLower(scope.SQL[0:strings.Index(scope.SQL, " ")]))
-
-		if client.tracer != nil {
-			if oldCtx, ok := scope.Get(keyCtx); ok {
-				ctx, ok = oldCtx.(context.Context)
-				if ok {
-					var childSp opentracing.Span
-					sp := opentracing.SpanFromContext(ctx)
-					if sp == nil {
-						childSp, ctx = opentracing.StartSpanFromContextWithTracer(
-							ctx,
-							client.tracer,
-							operationInfo,
-							opentracing.StartTime(beginTime.(time.Time)),
-						)
-					} else {
-						childSp = client.tracer.StartSpan(
-							operationInfo,
-							opentracing.ChildOf(sp.Context()),
-							opentracing.StartTime(beginTime.(time.Time)),
-						)
-					}
-
-					childSp.SetTag("sql", scope.SQL).
-						SetTag("args", scope.SQLVars).
-						SetTag("rowsAffected", scope.DB().RowsAffected).
-						SetTag("error", scope.DB().Error)
-					childSp.Finish()
-				} else {
-					client.logger.ContextErrorf(ctx, "mysql transform trace ctx fail: %v", oldCtx)
-				}
+		if scopeCtx, ok := scope.Get(keyCtx); ok {
+			if c, ok := scopeCtx.(context.Context); ok && c != nil {
+				ctx = c
 			} else {
-				client.logger.ContextErrorf(ctx, "mysql get trace ctx fail")
+				client.logger.ContextErrorf(ctx, "mysql transform trace ctx fail: %v", scopeCtx)
 			}
+		} else if client.tracer != nil {
+			client.logger.ContextErrorf(ctx, "mysql get trace ctx fail")
+		}
+
+		err := scope.DB().Error
+		if gorm.IsRecordNotFoundError(err) {
+			err = nil
+		}
+		if done, ok := scope.Get(keyBreaker); ok {
+			if f, ok := done.(func(error)); ok {
+				f(err)
+			}
+		}
+
+		verb := statement(scope.SQL)
+		operationInfo := fmt.Sprint(operation, strings.ToLower(verb))
+		r := redact.Or(client.redactor)
+
+		if client.tracer != nil {
+			var sp opentracing.Span
+			sp, ctx = opentrace.StartChild(ctx, client.tracer, operationInfo, opentracing.StartTime(beginTime), tags)
+
+			sql := r.String(scope.SQL)
+			sp.SetTag("sql", sql).
+				SetTag("args", r.SQLVars(scope.SQL, scope.SQLVars)).
+				SetTag("rowsAffected", scope.DB().RowsAffected)
+			opentrace.SetAttributes(sp,
+				semconv.DBOperation(strings.ToUpper(verb)),
+				semconv.DBSQLTable(scope.TableName()),
+				semconv.DBStatement(sql),
+			)
+			opentrace.Finish(sp, err)
 		}
 
 		if client.ableMonitor {
@@ -129,8 +203,25 @@ func NewClient(cfg Config, opts ...Option) (Client, func()) {
 			metricsMysqlDurationHistogram.WithLabelValues(client.db, scope.SQL).Observe(float64(duration.Milliseconds()))
 		}
 
-		client.logger.ContextInfof(ctx, fmt.Sprint(operation, "sql: ", scope.SQL, " , args: ", scope.SQLVars,
-			" , duration: ", duration.Milliseconds()))
+		if err == nil && duration < client.slowThreshold {
+			log.CountDropped("mysql", log.ReasonThreshold)
+			return
+		}
+
+		fields := []log.Field{
+			log.String("db", client.db),
+			log.String("sql", r.String(scope.SQL)),
+			log.Any("args", r.SQLVars(scope.SQL, scope.SQLVars)),
+			log.Int64("rows_affected", scope.DB().RowsAffected),
+			log.Duration("duration", duration),
+		}
+		logger := log.FromContextOr(ctx, client.logger).Named("mysql")
+		if err != nil {
+			logger.ContextErrorField(ctx, operationInfo, append(fields, log.Err(err))...)
+			return
+		}
+
+		logger.ContextInfoField(ctx, operationInfo, fields...)
 	}
 
 	db.Callback().Query().Before("gorm:query").Register("query-before-1", func(scope *gorm.Scope) {
@@ -168,12 +259,17 @@ func NewClient(cfg Config, opts ...Option) (Client, func()) {
 		scopeTrace(scope)
 	})
 
-	return client, func() {
-		if err = db.Close(); err != nil {
+	var once sync.Once
+	return client, func() error {
+		once.Do(func() {
 			close(client.close)
-			client.logger.Errorf("mysql close: %s", err.Error())
+		})
+
+		if err := db.Close(); err != nil {
+			return &Error{Kind: ErrClose, Err: err}
 		}
-	}
+		return nil
+	}, nil
 }
 
 func WithLogger(logger log.Logger) Option {
@@ -188,6 +284,34 @@ func WithTracer(tracer opentracing.Tracer) Option {
 	}
 }
 
+// WithSlowThreshold only logs the statements slower than threshold, failed statements are always logged.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(c *Client) {
+		c.slowThreshold = threshold
+	}
+}
+
+// WithRedactor masks the logged and traced statements with redactor instead of redact.Default().
+func WithRedactor(redactor *redact.Redactor) Option {
+	return func(c *Client) {
+		c.redactor = redactor
+	}
+}
+
+// WithBreaker fails the statements fast with breaker.ErrCircuitOpen while b is open.
+func WithBreaker(b *breaker.Breaker) Option {
+	return func(c *Client) {
+		c.breaker = b
+	}
+}
+
+// WithRetry retries the initial connection, by default the client fails on the first error.
+func WithRetry(policy backoff.Policy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
 func WithMonitor(able bool, interval time.Duration) Option {
 	return func(c *Client) {
 		c.ableMonitor = able
@@ -200,23 +324,57 @@ func (c Client) WithContext(ctx context.Context) Client {
 	return c
 }
 
+// SetMonitorInterval changes the PerformanceStats interval without restarting it.
+func (c Client) SetMonitorInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	select {
+	case <-c.reload:
+	default:
+	}
+
+	select {
+	case c.reload <- interval:
+	default:
+	}
+}
+
+// SetPool applies the pool size and connection lifetime of cfg to the open connections.
+func (c Client) SetPool(cfg Config) {
+	setPool(c.DB, cfg)
+}
+
+func setPool(db *gorm.DB, cfg Config) {
+	db.DB().SetConnMaxLifetime(time.Millisecond * time.Duration(cfg.MaxLifeTime))
+	db.DB().SetMaxIdleConns(cfg.MaxIdleConn)
+	db.DB().SetMaxOpenConns(cfg.MaxOpenConn)
+}
+
 func (c Client) PerformanceStats() {
 	if !c.ableMonitor {
 		return
 	}
 
 	c.logger.Infof("mysql performance statistics begin...")
+	c.performanceStats(c.monitorInterval)
+}
 
+// performanceStats restarts after a panic with the interval in use, the reloaded one if any.
+func (c Client) performanceStats(interval time.Duration) {
 	go func() {
 		defer func() {
 			if err := recover(); err != nil {
 				c.logger.Errorf("mysql performance stats: %v", err)
-				c.PerformanceStats()
+				c.performanceStats(interval)
 			}
 		}()
 
-		ticker := time.NewTicker(c.monitorInterval)
-		defer ticker.Stop()
+		ticker := time.NewTicker(interval)
+		defer func() {
+			ticker.Stop()
+		}()
 
 		for {
 			select {
@@ -230,6 +388,9 @@ func (c Client) PerformanceStats() {
 				metricsMysqlStatsGauge.WithLabelValues(c.db, "wait count").Set(float64(stats.WaitCount))
 				metricsMysqlStatsGauge.WithLabelValues(c.db, "wait duration").Set(float64(stats.WaitDuration))
 				metricsMysqlStatsGauge.WithLabelValues(c.db, "max lifetime closed").Set(float64(stats.MaxLifetimeClosed))
+			case interval = <-c.reload:
+				ticker.Stop()
+				ticker = time.NewTicker(interval)
 			case <-c.close:
 				c.logger.Infof("mysql stats stop...")
 				return
@@ -237,3 +398,22 @@ func (c Client) PerformanceStats() {
 		}
 	}()
 }
+
+// statement is the first word of sql, e.g. SELECT or a raw COMMIT, empty for an empty sql.
+func statement(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
+
+func addr(dsn string) string {
+	c, err := driver.ParseDSN(dsn)
+	if err != nil {
+		return ""
+	}
+
+	return c.Addr
+}
@@ -0,0 +1,21 @@
+package mysql
+
+import "testing"
+
+func TestStatement(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "SELECT * FROM `users` WHERE `id` = ?", want: "SELECT"},
+		{sql: "COMMIT", want: "COMMIT"},
+		{sql: "\n\tUPDATE `users` SET `name` = ?", want: "UPDATE"},
+		{sql: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := statement(tt.sql); got != tt.want {
+			t.Errorf("statement(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GaVender/era/pkg/log"
+)
+
+type (
+	Policy int
+
+	// Config picks the endpoints by Policy and resolves them again every RefreshInterval.
+	// An endpoint failing FailureThreshold calls in a row is ejected for EjectionTime,
+	// unless every endpoint is, then they are all picked from.
+	Config struct {
+		Policy           Policy
+		RefreshInterval  time.Duration
+		FailureThreshold int
+		EjectionTime     time.Duration
+	}
+
+	Balancer struct {
+		name       string
+		resolver   Resolver
+		cfg        Config
+		logger     log.Logger
+		now        func() time.Time
+		mu         sync.Mutex
+		endpoints  []*endpoint
+		next       int
+		resolvedAt time.Time
+		refreshing bool
+	}
+
+	endpoint struct {
+		Endpoint
+		outstanding  int
+		failures     int
+		ejectedUntil time.Time
+		current      int
+		removed      bool
+	}
+
+	Option func(*Balancer)
+)
+
+const (
+	PolicyRoundRobin Policy = iota
+	PolicyLeastOutstanding
+	PolicyWeighted
+
+	defaultRefreshInterval  = 30 * time.Second
+	defaultFailureThreshold = 5
+	defaultEjectionTime     = 30 * time.Second
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	ErrNoEndpoint = errors.New("no endpoint")
+)
+
+func New(name string, resolver Resolver, cfg Config, opts ...Option) *Balancer {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = defaultRefreshInterval
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.EjectionTime <= 0 {
+		cfg.EjectionTime = defaultEjectionTime
+	}
+
+	b := &Balancer{
+		name:     name,
+		resolver: resolver,
+		cfg:      cfg,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.logger == nil {
+		b.logger = log.NullLogger{}
+	}
+
+	return b
+}
+
+// WithLogger logs the failed refreshes and the ejections.
+func WithLogger(logger log.Logger) Option {
+	return func(b *Balancer) {
+		b.logger = logger
+	}
+}
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(b *Balancer) {
+		b.now = now
+	}
+}
+
+func (b *Balancer) Name() string {
+	return b.name
+}
+
+// Pick chooses an endpoint, the call made to it must be reported through done once it returns.
+// A call reported with context.Canceled or context.DeadlineExceeded counts neither as a failure nor as a success.
+// The endpoints are resolved while there are none, then refreshed in the background.
+func (b *Balancer) Pick(ctx context.Context) (addr string, done func(err error), err error) {
+	b.mu.Lock()
+	resolved := len(b.endpoints) > 0
+	b.mu.Unlock()
+
+	if !resolved {
+		if err := b.Refresh(ctx); err != nil {
+			return "", nil, err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.refreshIfStale(now)
+
+	e := b.choose(b.healthy(now))
+	if e == nil {
+		return "", nil, fmt.Errorf("balancer %s: %w", b.name, ErrNoEndpoint)
+	}
+
+	e.outstanding++
+	metricsBalancerOutstandingGauge.WithLabelValues(b.name, e.Addr).Inc()
+	return e.Addr, func(err error) {
+		b.done(e, err)
+	}, nil
+}
+
+// Refresh resolves the endpoints now, the previous ones are kept when it fails.
+func (b *Balancer) Refresh(ctx context.Context) error {
+	endpoints, err := b.resolver.Resolve(ctx)
+	if err == nil && len(endpoints) == 0 {
+		err = ErrNoEndpoint
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.resolvedAt = b.now()
+	if err != nil {
+		return fmt.Errorf("balancer %s: resolve: %w", b.name, err)
+	}
+
+	b.update(endpoints)
+	return nil
+}
+
+// Endpoints are the resolved endpoints, ejected or not.
+func (b *Balancer) Endpoints() []Endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	endpoints := make([]Endpoint, len(b.endpoints))
+	for i, e := range b.endpoints {
+		endpoints[i] = e.Endpoint
+	}
+
+	return endpoints
+}
+
+func (b *Balancer) refreshIfStale(now time.Time) {
+	if b.refreshing || now.Sub(b.resolvedAt) < b.cfg.RefreshInterval {
+		return
+	}
+	b.refreshing = true
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), b.cfg.RefreshInterval)
+		defer cancel()
+
+		err := b.Refresh(ctx)
+
+		b.mu.Lock()
+		b.refreshing = false
+		b.mu.Unlock()
+
+		if err != nil {
+			b.logger.Errorf(err.Error())
+		}
+	}()
+}
+
+// update replaces the endpoints, keeping the state of the ones still resolved.
+func (b *Balancer) update(endpoints []Endpoint) {
+	previous := make(map[string]*endpoint, len(b.endpoints))
+	for _, e := range b.endpoints {
+		previous[e.Addr] = e
+	}
+
+	b.endpoints = make([]*endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if ep.Weight <= 0 {
+			ep.Weight = 1
+		}
+
+		e, ok := previous[ep.Addr]
+		if ok {
+			delete(previous, ep.Addr)
+			e.Weight = ep.Weight
+		} else {
+			e = &endpoint{Endpoint: ep}
+			metricsBalancerEjectedGauge.WithLabelValues(b.name, ep.Addr).Set(0)
+		}
+		b.endpoints = append(b.endpoints, e)
+	}
+
+	for addr, e := range previous {
+		e.removed = true
+		metricsBalancerOutstandingGauge.DeleteLabelValues(b.name, addr)
+		metricsBalancerEjectedGauge.DeleteLabelValues(b.name, addr)
+	}
+}
+
+// healthy are the endpoints not ejected, all of them when they all are.
+func (b *Balancer) healthy(now time.Time) []*endpoint {
+	healthy := make([]*endpoint, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		if !e.ejectedUntil.IsZero() && !now.Before(e.ejectedUntil) {
+			e.ejectedUntil = time.Time{}
+			metricsBalancerEjectedGauge.WithLabelValues(b.name, e.Addr).Set(0)
+		}
+		if e.ejectedUntil.IsZero() {
+			healthy = append(healthy, e)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return b.endpoints
+	}
+	return healthy
+}
+
+func (b *Balancer) choose(endpoints []*endpoint) *endpoint {
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	start := b.next % len(endpoints)
+	b.next++
+
+	switch b.cfg.Policy {
+	case PolicyLeastOutstanding:
+		chosen := endpoints[start]
+		for i := 1; i < len(endpoints); i++ {
+			if e := endpoints[(start+i)%len(endpoints)]; e.outstanding < chosen.outstanding {
+				chosen = e
+			}
+		}
+		return chosen
+	case PolicyWeighted:
+		// smooth weighted round-robin, the heavier endpoints are picked more often without bursts.
+		var chosen *endpoint
+		total := 0
+		for _, e := range endpoints {
+			e.current += e.Weight
+			total += e.Weight
+			if chosen == nil || e.current > chosen.current {
+				chosen = e
+			}
+		}
+		chosen.current -= total
+		return chosen
+	default:
+		return endpoints[start]
+	}
+}
+
+func (b *Balancer) done(e *endpoint, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e.outstanding--
+	if e.removed {
+		return
+	}
+	metricsBalancerOutstandingGauge.WithLabelValues(b.name, e.Addr).Dec()
+
+	// the caller gave up, which tells nothing about the endpoint
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	if err == nil {
+		e.failures = 0
+		metricsBalancerRequestCounter.WithLabelValues(b.name, e.Addr, resultSuccess).Inc()
+		return
+	}
+
+	metricsBalancerRequestCounter.WithLabelValues(b.name, e.Addr, resultFailure).Inc()
+	e.failures++
+	if e.failures < b.cfg.FailureThreshold || !e.ejectedUntil.IsZero() {
+		return
+	}
+
+	e.failures = 0
+	e.ejectedUntil = b.now().Add(b.cfg.EjectionTime)
+	metricsBalancerEjectedGauge.WithLabelValues(b.name, e.Addr).Set(1)
+	b.logger.Warnf("balancer %s: endpoint %s ejected for %s after %d failures: %s",
+		b.name, e.Addr, b.cfg.EjectionTime, b.cfg.FailureThreshold, err.Error())
+}
@@ -0,0 +1,138 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestBalancerPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		resolver Static
+		hold     bool
+		want     []string
+	}{
+		{name: "round-robin", policy: PolicyRoundRobin, resolver: StaticAddrs("a:80", "b:80", "c:80"),
+			want: []string{"a:80", "b:80", "c:80", "a:80"}},
+		{name: "least outstanding", policy: PolicyLeastOutstanding, resolver: StaticAddrs("a:80", "b:80"), hold: true,
+			want: []string{"a:80", "b:80", "a:80", "b:80"}},
+		{name: "weighted", policy: PolicyWeighted, resolver: Static{{Addr: "a:80", Weight: 3}, {Addr: "b:80", Weight: 1}},
+			want: []string{"a:80", "a:80", "b:80", "a:80", "a:80", "a:80", "b:80", "a:80"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(tt.name, tt.resolver, Config{Policy: tt.policy})
+
+			var got []string
+			for range tt.want {
+				addr, done, err := b.Pick(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, addr)
+				if !tt.hold {
+					done(nil)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("picked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBalancerEjection(t *testing.T) {
+	c := &clock{now: time.Unix(1585000000, 0)}
+	b := New("ejection", StaticAddrs("a:80", "b:80"), Config{FailureThreshold: 2, EjectionTime: time.Minute, RefreshInterval: time.Hour},
+		WithClock(c.Now))
+	errDown := errors.New("down")
+
+	pick := func(err error) string {
+		addr, done, pickErr := b.Pick(context.Background())
+		if pickErr != nil {
+			t.Fatal(pickErr)
+		}
+		if addr == "a:80" {
+			done(err)
+		} else {
+			done(nil)
+		}
+		return addr
+	}
+
+	for i := 0; i < 4; i++ {
+		pick(errDown)
+	}
+	for i := 0; i < 3; i++ {
+		if addr := pick(nil); addr != "b:80" {
+			t.Errorf("picked ejected endpoint %s", addr)
+		}
+	}
+
+	c.now = c.now.Add(time.Minute)
+	if addr, addr2 := pick(nil), pick(nil); addr == addr2 {
+		t.Errorf("endpoint not restored: %s, %s", addr, addr2)
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era-balancer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "endpoints")
+	content := strings.Join([]string{"# local partner", "127.0.0.1:8081 3", "", "127.0.0.1:8082"}, "\n")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := File{Path: path}.Resolve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Endpoint{{Addr: "127.0.0.1:8081", Weight: 3}, {Addr: "127.0.0.1:8082"}}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("endpoints = %v, want %v", endpoints, want)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("127.0.0.1:8081 heavy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (File{Path: path}).Resolve(context.Background()); err == nil {
+		t.Error("invalid weight resolved")
+	}
+}
+
+func TestSRVEndpoints(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "backup.partner.", Port: 8080, Priority: 20, Weight: 1},
+		{Target: "a.partner.", Port: 8080, Priority: 10, Weight: 3},
+		{Target: "b.partner.", Port: 8081, Priority: 10, Weight: 1},
+	}
+
+	want := []Endpoint{{Addr: "a.partner:8080", Weight: 3}, {Addr: "b.partner:8081", Weight: 1}}
+	if got := srvEndpoints(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("endpoints = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package balancer
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	namespace = "era"
+	subsystem = "balancer"
+
+	metricsBalancerRequestCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "request_total",
+		Help:      "total number of calls to an endpoint by result",
+	}, []string{
+		"name",
+		"endpoint",
+		"result",
+	})
+
+	metricsBalancerOutstandingGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "outstanding",
+		Help:      "calls to an endpoint in flight",
+	}, []string{
+		"name",
+		"endpoint",
+	})
+
+	metricsBalancerEjectedGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "ejected",
+		Help:      "1 while an endpoint is ejected for failing",
+	}, []string{
+		"name",
+		"endpoint",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(metricsBalancerRequestCounter, metricsBalancerOutstandingGauge, metricsBalancerEjectedGauge)
+}
@@ -0,0 +1,127 @@
+package balancer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Endpoint is an instance of a service, Weight is only used by PolicyWeighted, 0 counts as 1.
+	Endpoint struct {
+		Addr   string
+		Weight int
+	}
+
+	// Resolver lists the endpoints of a service, it is called again on every refresh of the balancer.
+	Resolver interface {
+		Resolve(ctx context.Context) ([]Endpoint, error)
+	}
+
+	// Static always resolves to the same endpoints.
+	Static []Endpoint
+
+	// DNS resolves the SRV records of _Service._Proto.Name, e.g. Service "http", Proto "tcp".
+	// An empty Service and Proto look up Name directly. Only the records of the lowest priority value
+	// are used, as in RFC 2782, the backup targets get no traffic while they resolve.
+	DNS struct {
+		Service  string
+		Proto    string
+		Name     string
+		Resolver *net.Resolver
+	}
+
+	// File reads one endpoint per line of the file at Path, as "host:port [weight]",
+	// blank lines and the lines starting with # are skipped. Meant for local runs.
+	File struct {
+		Path string
+	}
+)
+
+// StaticAddrs is a Static of addrs with the same weight.
+func StaticAddrs(addrs ...string) Static {
+	s := make(Static, len(addrs))
+	for i, addr := range addrs {
+		s[i] = Endpoint{Addr: addr}
+	}
+
+	return s
+}
+
+func (s Static) Resolve(context.Context) ([]Endpoint, error) {
+	return append([]Endpoint(nil), s...), nil
+}
+
+func (d DNS) Resolve(ctx context.Context) ([]Endpoint, error) {
+	r := d.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	_, records, err := r.LookupSRV(ctx, d.Service, d.Proto, d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return srvEndpoints(records), nil
+}
+
+// srvEndpoints are the endpoints of the records with the lowest priority value.
+func srvEndpoints(records []*net.SRV) []Endpoint {
+	if len(records) == 0 {
+		return nil
+	}
+
+	lowest := records[0].Priority
+	for _, srv := range records {
+		if srv.Priority < lowest {
+			lowest = srv.Priority
+		}
+	}
+
+	var endpoints []Endpoint
+	for _, srv := range records {
+		if srv.Priority != lowest {
+			continue
+		}
+
+		host := strings.TrimSuffix(srv.Target, ".")
+		endpoints = append(endpoints, Endpoint{
+			Addr:   net.JoinHostPort(host, strconv.Itoa(int(srv.Port))),
+			Weight: int(srv.Weight),
+		})
+	}
+
+	return endpoints
+}
+
+func (f File) Resolve(context.Context) ([]Endpoint, error) {
+	b, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []Endpoint
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		e := Endpoint{Addr: fields[0]}
+		if len(fields) > 1 {
+			if e.Weight, err = strconv.Atoi(fields[1]); err != nil || e.Weight < 0 {
+				return nil, fmt.Errorf("%s:%d: invalid weight %q", f.Path, line, fields[1])
+			}
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints, scanner.Err()
+}
@@ -0,0 +1,55 @@
+package ehttp
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/GaVender/cast"
+
+	"github.com/GaVender/era/pkg/net/balancer"
+)
+
+// WithBalancer sends the requests of c to the endpoints of b under baseURL, e.g. "http://partner/v1",
+// whose host only names the service in the spans, logs and metrics. c must be created without a base URL.
+// The transport errors and the 5xx responses count as failures of the endpoint.
+func (c *Client) WithBalancer(b *balancer.Balancer, baseURL string) *Client {
+	if len(c.GetBaseURL()) > 0 {
+		panic("ehttp: balanced client with a base URL")
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+		panic("ehttp: invalid balancer base URL " + baseURL)
+	}
+
+	c.balancer = b
+	c.balancedURL = u
+	return c
+}
+
+// baseURL is the base URL of cast, or the one of the balancer.
+func (c *Client) baseURL() string {
+	if c.balancedURL != nil {
+		return c.balancedURL.String()
+	}
+
+	return c.GetBaseURL()
+}
+
+// pick points request at an endpoint of the balancer, release points it back and reports the outcome of the attempt.
+func (c *Client) pick(ctx context.Context, request *cast.Request) (addr string, release func(err error), err error) {
+	addr, done, err := c.balancer.Pick(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	path := request.GetPath()
+	u := *c.balancedURL
+	u.Host = addr
+	request.WithPath(u.String() + path)
+
+	return addr, func(err error) {
+		request.WithPath(path)
+		done(err)
+	}, nil
+}
@@ -0,0 +1,60 @@
+package ehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/GaVender/era/pkg/net/balancer"
+)
+
+func TestClientBalancer(t *testing.T) {
+	var healthyCalls, failingCalls int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&healthyCalls, 1)
+		if r.URL.Path != "/v1/users/42" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failingCalls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	b := balancer.New("partner", balancer.StaticAddrs(strings.TrimPrefix(healthy.URL, "http://"), strings.TrimPrefix(failing.URL, "http://")),
+		balancer.Config{FailureThreshold: 2, EjectionTime: time.Minute})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := mocktracer.New()
+	client.WithTracer(tracer).WithBalancer(b, "http://partner/v1")
+
+	for i := 0; i < 8; i++ {
+		resp, err := client.Send(context.Background(), client.NewRequest().WithPath("/users/42"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := resp.StatusCode(); status != http.StatusOK && status != http.StatusInternalServerError {
+			t.Errorf("request %d: status %d", i, status)
+		}
+	}
+
+	if atomic.LoadInt32(&healthyCalls) != 6 || atomic.LoadInt32(&failingCalls) != 2 {
+		t.Errorf("healthy calls %d, failing calls %d", healthyCalls, failingCalls)
+	}
+
+	sp := tracer.FinishedSpans()[0]
+	if sp.OperationName != operation+"/v1" || sp.Tag("url") != "http://partner/v1" || sp.Tag("peer.address") == nil {
+		t.Errorf("span %s: %v", sp.OperationName, sp.Tags())
+	}
+}
@@ -3,21 +3,54 @@ package ehttp
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/GaVender/cast"
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 
+	"github.com/GaVender/era/pkg/breaker"
 	"github.com/GaVender/era/pkg/log"
+	"github.com/GaVender/era/pkg/net/balancer"
+	"github.com/GaVender/era/pkg/opentrace"
+	"github.com/GaVender/era/pkg/redact"
 )
 
 type (
+	// Doer sends the requests built by NewRequest, *Client is one, ehttptest serves fakes to it.
+	Doer interface {
+		NewRequest() *cast.Request
+		Send(ctx context.Context, request *cast.Request) (*cast.Response, error)
+	}
+
 	Client struct {
 		*cast.Cast
-		tracer      opentracing.Tracer
-		logger      log.Logger
-		ableMonitor bool
+		tracer         opentracing.Tracer
+		logger         log.Logger
+		ableMonitor    bool
+		slowThreshold  time.Duration
+		redactor       *redact.Redactor
+		retry          *RetryPolicy
+		breaker        *breaker.Breaker
+		rateLimit      *RateLimit
+		hostRateLimits map[string]*RateLimit
+		balancer       *balancer.Balancer
+		balancedURL    *url.URL
+		normalizer     RouteNormalizer
+		tagBodyLimit   int
+		logBodyLimit   int
+		failOnStatus   bool
+		errorDecoder   ErrorDecoder
+	}
+
+	// target is the host and the route template a request is labelled with.
+	target struct {
+		host  string
+		route string
 	}
 )
 
@@ -25,6 +58,8 @@ const (
 	operation = "http: "
 )
 
+var _ Doer = (*Client)(nil)
+
 func NewClient(ss ...cast.Setter) (*Client, error) {
 	c, err := cast.New(ss...)
 	if err != nil {
@@ -32,8 +67,10 @@ func NewClient(ss ...cast.Setter) (*Client, error) {
 	}
 
 	client := Client{
-		Cast:   c,
-		logger: log.NullLogger{},
+		Cast:         c,
+		logger:       log.NullLogger{},
+		tagBodyLimit: DefaultTagBodyLimit,
+		logBodyLimit: DefaultLogBodyLimit,
 	}
 
 	return &client, nil
@@ -49,6 +86,31 @@ func (c *Client) WithLogger(logger log.Logger) *Client {
 	return c
 }
 
+// WithSlowThreshold only logs the requests slower than threshold, failed requests are always logged.
+func (c *Client) WithSlowThreshold(threshold time.Duration) *Client {
+	c.slowThreshold = threshold
+	return c
+}
+
+// WithRedactor masks the traced requests and responses with redactor instead of redact.Default().
+func (c *Client) WithRedactor(redactor *redact.Redactor) *Client {
+	c.redactor = redactor
+	return c
+}
+
+// WithBreaker fails the requests fast with breaker.ErrCircuitOpen while b is open,
+// the transport errors and the 5xx responses count as failures, the requests canceled by the caller don't count.
+func (c *Client) WithBreaker(b *breaker.Breaker) *Client {
+	c.breaker = b
+	return c
+}
+
+// WithRouteNormalizer labels the metrics with the routes of normalizer instead of NormalizeRoute.
+func (c *Client) WithRouteNormalizer(normalizer RouteNormalizer) *Client {
+	c.normalizer = normalizer
+	return c
+}
+
 func (c *Client) WithMonitor(able bool) *Client {
 	c.ableMonitor = able
 	return c
@@ -56,7 +118,7 @@ func (c *Client) WithMonitor(able bool) *Client {
 
 func (c *Client) Send(ctx context.Context, request *cast.Request) (resp *cast.Response, err error) {
 	beginTime := time.Now()
-	urlStr := c.GetBaseURL()
+	urlStr := c.baseURL()
 	urlInfo, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
@@ -65,49 +127,203 @@ func (c *Client) Send(ctx context.Context, request *cast.Request) (resp *cast.Re
 	operationInfo := operation + urlInfo.Path
 
 	if c.tracer != nil {
-		var childSp opentracing.Span
-		sp := opentracing.SpanFromContext(ctx)
-		if sp == nil {
-			childSp, ctx = opentracing.StartSpanFromContextWithTracer(
-				ctx,
-				c.tracer,
-				operationInfo,
-				opentracing.StartTime(beginTime),
-			)
-		} else {
-			childSp = c.tracer.StartSpan(
-				operationInfo,
-				opentracing.ChildOf(sp.Context()),
-				opentracing.StartTime(beginTime),
-			)
-		}
+		var sp opentracing.Span
+		fullURL := urlInfo.Scheme + "://" + urlInfo.Host + urlInfo.Path
+		sp, ctx = opentrace.StartChild(ctx, c.tracer, operationInfo,
+			opentracing.StartTime(beginTime), opentrace.HTTPClientTags(request.GetMethod(), fullURL, urlInfo.Host))
 
 		carrier := opentracing.HTTPHeadersCarrier(request.GetHeader())
-		if err := c.tracer.Inject(childSp.Context(), opentracing.HTTPHeaders, carrier); err != nil {
+		if err := c.tracer.Inject(sp.Context(), opentracing.HTTPHeaders, carrier); err != nil {
 			c.logger.ContextErrorf(ctx, "http request carrier fail")
 		}
 
 		defer func() {
-			childSp.SetTag("url", c.GetBaseURL()).
+			r := redact.Or(c.redactor)
+			header := request.GetHeader()
+			if raw := request.RawRequest(); raw != nil {
+				header = raw.Header
+			}
+			body, _ := request.ReqBody()
+			sp.SetTag("url", c.baseURL()).
 				SetTag("method", request.GetMethod()).
-				SetTag("header", request.RawRequest().Header).
-				SetTag("query", urlInfo.Query()).
-				SetTag("body", string(request.GetBody())).
-				SetTag("status code", resp.StatusCode()).
-				SetTag("response", string(resp.Body()))
-			childSp.Finish()
+				SetTag("header", r.Header(header)).
+				SetTag("query", r.Query(urlInfo.Query())).
+				SetTag("body", capture(r, body, c.tagBodyLimit))
+
+			if resp != nil {
+				sp.SetTag("status code", resp.StatusCode()).
+					SetTag("response", capture(r, resp.Body(), c.tagBodyLimit))
+				opentrace.SetAttributes(sp, semconv.HTTPResponseStatusCode(resp.StatusCode()))
+				if resp.StatusCode() >= http.StatusInternalServerError {
+					ext.Error.Set(sp, true)
+				}
+			}
+			if err != nil {
+				opentrace.SetAttributes(sp, semconv.ErrorTypeKey.String(errorClass(err)))
+			}
+			opentrace.Finish(sp, err)
 		}()
 	}
 
+	t := c.target(request)
 	if c.ableMonitor {
 		defer func() {
-			metricsHttpRequestCounter.WithLabelValues(c.GetBaseURL()).Inc()
-			metricsHttpRequestDurationHistogram.WithLabelValues(c.GetBaseURL()).
-				Observe(float64(time.Now().Sub(beginTime).Milliseconds()))
+			clientHistogram().WithLabelValues(t.host, t.route, request.GetMethod(), statusClass(resp)).
+				Observe(time.Now().Sub(beginTime).Seconds())
 		}()
 	}
 
-	resp, err = c.Do(ctx, request)
-	c.logger.ContextInfof(ctx, fmt.Sprint(operationInfo, " , duration: ", time.Now().Sub(beginTime).Milliseconds()))
+	policy := c.retryPolicy(ctx, request)
+	attempts := 0
+	for {
+		attempts++
+		resp, err = c.attempt(ctx, request, operationInfo, t, attempts, policy.Attempts > 1)
+
+		wait, ok := policy.next(ctx, attempts, resp, err)
+		if !ok {
+			break
+		}
+
+		retryFields := []log.Field{
+			log.String("url", urlInfo.Scheme+"://"+urlInfo.Host+urlInfo.Path),
+			log.String("method", request.GetMethod()),
+			log.Int("attempt", attempts),
+			log.Duration("wait", wait),
+		}
+		if err != nil {
+			retryFields = append(retryFields, log.Err(err))
+		} else {
+			retryFields = append(retryFields, log.Int("status", resp.StatusCode()))
+		}
+		log.FromContextOr(ctx, c.logger).Named("ehttp").ContextWarnField(ctx, "http retry", retryFields...)
+
+		if !sleep(ctx, wait) {
+			break
+		}
+	}
+
+	if err == nil && c.failOnStatus {
+		err = c.statusError(resp)
+	}
+
+	duration := time.Now().Sub(beginTime)
+	if err == nil && duration < c.slowThreshold {
+		log.CountDropped("ehttp", log.ReasonThreshold)
+		return
+	}
+
+	fields := []log.Field{
+		log.String("url", urlInfo.Scheme+"://"+urlInfo.Host+urlInfo.Path),
+		log.String("method", request.GetMethod()),
+		log.Duration("duration", duration),
+		log.Int("attempts", attempts),
+	}
+	if resp != nil {
+		fields = append(fields, log.Int("status", resp.StatusCode()))
+		if resp.StatusCode() >= http.StatusBadRequest && c.logBodyLimit >= 0 {
+			fields = append(fields, log.String("response", capture(redact.Or(c.redactor), resp.Body(), c.logBodyLimit)))
+		}
+	}
+
+	logger := log.FromContextOr(ctx, c.logger).Named("ehttp")
+	if err != nil {
+		logger.ContextErrorField(ctx, operationInfo, append(fields, log.Err(err))...)
+		return
+	}
+
+	logger.ContextInfoField(ctx, operationInfo, fields...)
 	return
 }
+
+// attempt sends request once, under a span of its own when it may be retried.
+func (c *Client) attempt(ctx context.Context, request *cast.Request, operationInfo string, t target, attempt int, retried bool) (resp *cast.Response, err error) {
+	if c.ableMonitor {
+		defer func() {
+			metricsHttpRequestCounter.WithLabelValues(t.host, t.route, request.GetMethod(),
+				statusClass(resp), errorClass(err), strconv.Itoa(attempt-1)).Inc()
+		}()
+	}
+
+	if err := c.acquire(ctx, t); err != nil {
+		return nil, err
+	}
+
+	var peer string
+	if c.balancer != nil {
+		addr, release, unavailable := c.pick(ctx, request)
+		if unavailable != nil {
+			return nil, unavailable
+		}
+		defer func() {
+			release(failure(ctx, resp, err))
+		}()
+
+		peer = addr
+		if sp := opentracing.SpanFromContext(ctx); sp != nil {
+			ext.PeerAddress.Set(sp, peer)
+		}
+	}
+
+	if c.breaker != nil {
+		done, rejected := c.breaker.Allow()
+		if rejected != nil {
+			return nil, rejected
+		}
+		defer func() {
+			done(failure(ctx, resp, err))
+		}()
+	}
+
+	if c.tracer == nil || !retried {
+		return c.Do(ctx, request)
+	}
+
+	sp, ctx := opentrace.StartChild(ctx, c.tracer, operationInfo+" attempt", opentracing.Tag{
+		Key: string(ext.SpanKind), Value: ext.SpanKindRPCClientEnum,
+	})
+	opentrace.SetAttributes(sp, semconv.HTTPRequestResendCount(attempt-1))
+	if len(peer) > 0 {
+		ext.PeerAddress.Set(sp, peer)
+	}
+
+	carrier := opentracing.HTTPHeadersCarrier(request.GetHeader())
+	if err := c.tracer.Inject(sp.Context(), opentracing.HTTPHeaders, carrier); err != nil {
+		c.logger.ContextErrorf(ctx, "http request carrier fail")
+	}
+
+	resp, err = c.Do(ctx, request)
+	if resp != nil {
+		opentrace.SetAttributes(sp, semconv.HTTPResponseStatusCode(resp.StatusCode()))
+	}
+	opentrace.Finish(sp, err)
+
+	return resp, err
+}
+
+// failure is the outcome of an attempt for the breaker and the balancer, the 5xx responses are failures.
+// An attempt the caller gave up on is the error of ctx, which neither counts as a failure.
+func failure(ctx context.Context, resp *cast.Response, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == nil && resp != nil && resp.StatusCode() >= http.StatusInternalServerError {
+		return fmt.Errorf("status %d", resp.StatusCode())
+	}
+
+	return err
+}
+
+// target labels request by the host and the normalized path it is sent to, without the query string.
+func (c *Client) target(request *cast.Request) target {
+	normalizer := c.normalizer
+	if normalizer == nil {
+		normalizer = NormalizeRoute
+	}
+
+	u, err := url.Parse(c.baseURL() + request.GetPath())
+	if err != nil {
+		return target{route: RouteNotFound}
+	}
+
+	return target{host: u.Host, route: normalizer(u.Path)}
+}
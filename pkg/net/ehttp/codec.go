@@ -0,0 +1,227 @@
+package ehttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/GaVender/cast"
+	"google.golang.org/protobuf/proto"
+)
+
+type (
+	// APIError is returned by the typed helpers on a non-2xx response, Code and Message are decoded
+	// from the response body by the ErrorDecoder of the client, DecodeAPIError by default.
+	APIError struct {
+		StatusError
+		Code    string
+		Message string
+	}
+
+	// ErrorDecoder fills the Code and Message of e from the body of a failed response.
+	ErrorDecoder func(body []byte, e *APIError)
+
+	// File is a file part of a multipart upload, ContentType defaults to application/octet-stream.
+	File struct {
+		Field       string
+		Name        string
+		ContentType string
+		Reader      io.Reader
+	}
+)
+
+const (
+	HeaderAccept      = "Accept"
+	HeaderContentType = "Content-Type"
+
+	ContentTypeJSON  = "application/json"
+	ContentTypeProto = "application/x-protobuf"
+	ContentTypeForm  = "application/x-www-form-urlencoded"
+)
+
+var (
+	quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+)
+
+// WithErrorDecoder decodes the error bodies of the typed helpers with decoder instead of DecodeAPIError.
+func (c *Client) WithErrorDecoder(decoder ErrorDecoder) *Client {
+	c.errorDecoder = decoder
+	return c
+}
+
+// GetJSON sends a GET to path and decodes the JSON response into out, if not nil.
+func (c *Client) GetJSON(ctx context.Context, path string, out interface{}) error {
+	return c.call(ctx, c.NewRequest().Get().WithPath(path), ContentTypeJSON, jsonDecoder(out))
+}
+
+// PostJSON posts in as JSON to path and decodes the JSON response into out, if not nil.
+func (c *Client) PostJSON(ctx context.Context, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encode json request: %w", err)
+	}
+
+	return c.call(ctx, c.NewRequest().Post().WithPath(path).WithCustomBody(ContentTypeJSON, body),
+		ContentTypeJSON, jsonDecoder(out))
+}
+
+// PostProto posts in as protobuf to path and decodes the protobuf response into out, if not nil.
+func (c *Client) PostProto(ctx context.Context, path string, in, out proto.Message) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encode proto request: %w", err)
+	}
+
+	var decode func([]byte) error
+	if out != nil {
+		decode = func(b []byte) error {
+			return proto.Unmarshal(b, out)
+		}
+	}
+
+	return c.call(ctx, c.NewRequest().Post().WithPath(path).WithCustomBody(ContentTypeProto, body),
+		ContentTypeProto, decode)
+}
+
+// PostForm posts form url-encoded to path and decodes the JSON response into out, if not nil.
+func (c *Client) PostForm(ctx context.Context, path string, form url.Values, out interface{}) error {
+	return c.call(ctx, c.NewRequest().Post().WithPath(path).WithCustomBody(ContentTypeForm, []byte(form.Encode())),
+		ContentTypeJSON, jsonDecoder(out))
+}
+
+// PostMultipart uploads fields and files as multipart/form-data to path and decodes the JSON response into out, if not nil.
+// The parts are built in memory.
+func (c *Client) PostMultipart(ctx context.Context, path string, fields url.Values, files []File, out interface{}) error {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range fields[k] {
+			if err := w.WriteField(k, v); err != nil {
+				return fmt.Errorf("encode multipart request: %w", err)
+			}
+		}
+	}
+
+	for _, f := range files {
+		contentType := f.ContentType
+		if len(contentType) == 0 {
+			contentType = "application/octet-stream"
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(f.Field), quoteEscaper.Replace(f.Name)))
+		header.Set(HeaderContentType, contentType)
+
+		part, err := w.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, f.Reader)
+		}
+		if err != nil {
+			return fmt.Errorf("encode multipart request: %s: %w", f.Name, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("encode multipart request: %w", err)
+	}
+
+	return c.call(ctx, c.NewRequest().Post().WithPath(path).WithCustomBody(w.FormDataContentType(), buf.Bytes()),
+		ContentTypeJSON, jsonDecoder(out))
+}
+
+// DecodeAPIError reads a JSON body like {"code": ..., "message": ...}, "msg" and a string "error"
+// are also taken as the message. A body it cannot read leaves e as is.
+func DecodeAPIError(body []byte, e *APIError) {
+	var v struct {
+		Code    interface{}     `json:"code"`
+		Message string          `json:"message"`
+		Msg     string          `json:"msg"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return
+	}
+
+	if v.Code != nil {
+		e.Code = fmt.Sprint(v.Code)
+	}
+
+	var errMsg string
+	_ = json.Unmarshal(v.Error, &errMsg)
+	for _, msg := range []string{v.Message, v.Msg, errMsg} {
+		if len(msg) > 0 {
+			e.Message = msg
+			break
+		}
+	}
+}
+
+func (e *APIError) Error() string {
+	msg := e.StatusError.Error()
+	if len(e.Code) > 0 {
+		msg += ": " + e.Code
+	}
+	if len(e.Message) > 0 {
+		msg += ": " + e.Message
+	}
+
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return &e.StatusError
+}
+
+// call sends request with Send, a 2xx response is decoded with decode and a non-2xx one fails with an *APIError.
+func (c *Client) call(ctx context.Context, request *cast.Request, accept string, decode func([]byte) error) error {
+	request.SetHeader(HeaderAccept, accept)
+
+	resp, err := c.Send(ctx, request)
+	if err != nil && !errors.Is(err, ErrStatus) {
+		return err
+	}
+
+	if statusErr := c.statusError(resp); statusErr != nil {
+		apiErr := &APIError{StatusError: *statusErr.(*StatusError)}
+		decoder := c.errorDecoder
+		if decoder == nil {
+			decoder = DecodeAPIError
+		}
+		decoder(resp.Body(), apiErr)
+		return apiErr
+	}
+
+	if decode == nil || len(resp.Body()) == 0 {
+		return nil
+	}
+	if err := decode(resp.Body()); err != nil {
+		return fmt.Errorf("decode %s response: %w", accept, err)
+	}
+
+	return nil
+}
+
+func jsonDecoder(out interface{}) func([]byte) error {
+	if out == nil {
+		return nil
+	}
+
+	return func(b []byte) error {
+		return json.Unmarshal(b, out)
+	}
+}
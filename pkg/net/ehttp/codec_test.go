@@ -0,0 +1,107 @@
+package ehttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/GaVender/cast"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestClientCodec(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			w.Header().Set(HeaderContentType, r.Header.Get(HeaderContentType))
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(body)
+		case "/form":
+			r.ParseForm()
+			json.NewEncoder(w).Encode(map[string]string{"name": r.PostForm.Get("name")})
+		case "/upload":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f, header, _ := r.FormFile("file")
+			content, _ := ioutil.ReadAll(f)
+			json.NewEncoder(w).Encode(map[string]string{"name": r.FormValue("name") + " " + header.Filename + " " + string(content)})
+		case "/user":
+			w.Write([]byte(`{"name": "alice"}`))
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"code": 4001, "message": "invalid user"}`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(cast.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name string
+		call func(out *user) error
+		want string
+	}{
+		{name: "get json", call: func(out *user) error {
+			return client.GetJSON(context.Background(), "/user", out)
+		}, want: "alice"},
+		{name: "post json", call: func(out *user) error {
+			return client.PostJSON(context.Background(), "/echo", user{Name: "bob"}, out)
+		}, want: "bob"},
+		{name: "post form", call: func(out *user) error {
+			return client.PostForm(context.Background(), "/form", url.Values{"name": {"carol"}}, out)
+		}, want: "carol"},
+		{name: "post multipart", call: func(out *user) error {
+			return client.PostMultipart(context.Background(), "/upload", url.Values{"name": {"dave"}},
+				[]File{{Field: "file", Name: "avatar.txt", ContentType: "text/plain", Reader: strings.NewReader("smile")}}, out)
+		}, want: "dave avatar.txt smile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out user
+			if err := tt.call(&out); err != nil {
+				t.Fatal(err)
+			}
+			if out.Name != tt.want {
+				t.Errorf("name = %q, want %q", out.Name, tt.want)
+			}
+		})
+	}
+
+	t.Run("post proto", func(t *testing.T) {
+		out := &wrapperspb.StringValue{}
+		if err := client.PostProto(context.Background(), "/echo", wrapperspb.String("erin"), out); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(out, wrapperspb.String("erin")) {
+			t.Errorf("out = %v", out)
+		}
+	})
+
+	t.Run("api error", func(t *testing.T) {
+		var apiErr *APIError
+		err := client.GetJSON(context.Background(), "/missing", nil)
+		if !errors.As(err, &apiErr) || !errors.Is(err, ErrStatus) {
+			t.Fatalf("err = %v", err)
+		}
+		if apiErr.Status != http.StatusUnprocessableEntity || apiErr.Code != "4001" || apiErr.Message != "invalid user" {
+			t.Errorf("api error = %+v", apiErr)
+		}
+	})
+}
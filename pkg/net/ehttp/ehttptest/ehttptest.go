@@ -0,0 +1,67 @@
+// Package ehttptest fakes the upstreams of ehttp clients: Mock replies from scripted stubs
+// and Recorder replays the exchanges it recorded in a cassette.
+package ehttptest
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/GaVender/cast"
+
+	"github.com/GaVender/era/pkg/net/ehttp"
+)
+
+// NewClient is an ehttp client sending its requests to rt.
+// cast has no pluggable transport, so rt is served on a loopback listener the client is pointed at,
+// the returned func closes it.
+func NewClient(rt http.RoundTripper, ss ...cast.Setter) (*ehttp.Client, func()) {
+	srv := httptest.NewServer(Handler(rt))
+
+	client, err := ehttp.NewClient(append(ss, cast.WithBaseURL(srv.URL))...)
+	if err != nil {
+		srv.Close()
+		panic("ehttptest client: " + err.Error())
+	}
+
+	return client, srv.Close
+}
+
+// Handler serves rt, the connection is dropped when rt fails.
+func Handler(rt http.RoundTripper) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := r.Clone(r.Context())
+		req.RequestURI = ""
+
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			drop(w)
+			return
+		}
+		defer resp.Body.Close()
+
+		for k, vv := range resp.Header {
+			w.Header()[k] = vv
+		}
+		w.Header().Del("Content-Length")
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	})
+}
+
+func drop(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
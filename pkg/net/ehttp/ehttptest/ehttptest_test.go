@@ -0,0 +1,155 @@
+package ehttptest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GaVender/cast"
+)
+
+func TestMock(t *testing.T) {
+	tests := []struct {
+		name       string
+		stub       func(m *Mock)
+		method     string
+		path       string
+		body       string
+		timeout    time.Duration
+		wantStatus []int
+		wantErr    bool
+	}{
+		{name: "scripted", stub: func(m *Mock) {
+			m.On(http.MethodGet, "/users/:id").Reply(http.StatusServiceUnavailable, "").Reply(http.StatusOK, "alice")
+		}, method: http.MethodGet, path: "/users/42", wantStatus: []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusOK}},
+		{name: "body", stub: func(m *Mock) {
+			m.On(http.MethodPost, "/orders").WithBody("a").Reply(http.StatusCreated, "")
+			m.On(http.MethodPost, "/orders").Reply(http.StatusConflict, "")
+		}, method: http.MethodPost, path: "/orders", body: "b", wantStatus: []int{http.StatusConflict}},
+		{name: "catch-all", stub: func(m *Mock) {
+			m.On("", "/static/*").Reply(http.StatusNoContent, "")
+		}, method: http.MethodDelete, path: "/static/css/app.css", wantStatus: []int{http.StatusNoContent}},
+		{name: "unmatched", stub: func(m *Mock) {
+			m.On(http.MethodGet, "/users").Reply(http.StatusOK, "")
+		}, method: http.MethodGet, path: "/users/42", wantStatus: []int{http.StatusNotImplemented}},
+		{name: "dropped", stub: func(m *Mock) {
+			m.On(http.MethodGet, "/users").Drop()
+		}, method: http.MethodGet, path: "/users", wantErr: true},
+		{name: "delayed", stub: func(m *Mock) {
+			m.On(http.MethodGet, "/users").Delay(time.Second)
+		}, method: http.MethodGet, path: "/users", timeout: 20 * time.Millisecond, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMock()
+			tt.stub(m)
+
+			client, closer := NewClient(m)
+			defer closer()
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			if tt.wantErr {
+				if _, err := client.Send(ctx, client.NewRequest().Method(tt.method).WithPath(tt.path)); err == nil {
+					t.Error("Send() succeeded")
+				}
+				return
+			}
+
+			for i, want := range tt.wantStatus {
+				resp, err := client.Send(ctx, client.NewRequest().Method(tt.method).WithPath(tt.path).WithPlainBody(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode() != want {
+					t.Errorf("request %d: status = %d, want %d", i, resp.StatusCode(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestMockRoundTrip(t *testing.T) {
+	m := NewMock()
+	stub := m.On(http.MethodGet, "/ping").Reply(http.StatusOK, "pong")
+	m.On(http.MethodGet, "/down").Drop()
+
+	client := &http.Client{Transport: m}
+	resp, err := client.Get("http://partner/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "pong" || stub.Calls() != 1 {
+		t.Errorf("body = %q, calls = %d", body, stub.Calls())
+	}
+
+	if _, err := client.Get("http://partner/down"); !errors.Is(err, ErrDropped) {
+		t.Errorf("dropped = %v", err)
+	}
+	if _, err := client.Get("http://partner/missing?q=1"); err != nil || len(m.Unmatched()) != 1 || m.Unmatched()[0] != "GET /missing?q=1" {
+		t.Errorf("unmatched = %v, %v", m.Unmatched(), err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era-ehttptest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cassette := filepath.Join(dir, "partner.json")
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Partner", "1")
+		w.Header().Set("Set-Cookie", "session=s3cr3t")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write(append([]byte(r.URL.RequestURI()+" "), body...))
+	}))
+
+	send := func(mode Mode) *cast.Response {
+		r, err := NewRecorder(cassette, mode, upstream.URL+"/v1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, closer := NewClient(r)
+		defer closer()
+
+		resp, err := client.Send(context.Background(), client.NewRequest().Post().WithPath("/orders?id=7").WithPlainBody("book"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	recorded := send(ModeRecord)
+	upstream.Close()
+	replayed := send(ModeReplay)
+
+	if recorded.String() != "/v1/orders?id=7 book" || replayed.String() != recorded.String() {
+		t.Errorf("recorded %q, replayed %q", recorded.String(), replayed.String())
+	}
+	if replayed.StatusCode() != http.StatusAccepted || replayed.Header().Get("X-Partner") != "1" {
+		t.Errorf("replayed status %d, header %v", replayed.StatusCode(), replayed.Header())
+	}
+	if b, _ := ioutil.ReadFile(cassette); strings.Contains(string(b), "s3cr3t") {
+		t.Errorf("cassette records a credential: %s", b)
+	}
+}
@@ -0,0 +1,231 @@
+package ehttptest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Mock replies to a request with the first stub matching it,
+	// the requests no stub matches get a 501 and are kept in Unmatched.
+	Mock struct {
+		mu        sync.Mutex
+		stubs     []*Stub
+		unmatched []string
+	}
+
+	// Stub replies with its responses in order, the last one is repeated once they run out.
+	// Its path may hold ":param" segments, matching any segment, and a trailing "*", matching the rest.
+	Stub struct {
+		mock      *Mock
+		method    string
+		path      string
+		body      func([]byte) bool
+		delay     time.Duration
+		responses []Response
+		calls     int
+	}
+
+	// Response is scripted on a stub, Drop fails the request with a dropped connection instead.
+	Response struct {
+		Status int
+		Header http.Header
+		Body   []byte
+		Drop   bool
+	}
+)
+
+var (
+	ErrDropped = errors.New("ehttptest: connection dropped")
+)
+
+func NewMock() *Mock {
+	return &Mock{}
+}
+
+// On stubs the requests of method, any method when empty, to path.
+func (m *Mock) On(method, path string) *Stub {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := &Stub{mock: m, method: method, path: path}
+	m.stubs = append(m.stubs, s)
+	return s
+}
+
+// Unmatched lists the requests no stub matched, as "METHOD /path?query".
+func (m *Mock) Unmatched() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.unmatched...)
+}
+
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	resp, delay, ok := m.next(req, body)
+	if !ok {
+		return reply(req, Response{
+			Status: http.StatusNotImplemented,
+			Body:   []byte(fmt.Sprintf("ehttptest: no stub for %s %s", req.Method, req.URL.RequestURI())),
+		}), nil
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if resp.Drop {
+		return nil, ErrDropped
+	}
+
+	return reply(req, resp), nil
+}
+
+func (m *Mock) next(req *http.Request, body []byte) (Response, time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.stubs {
+		if !s.match(req, body) {
+			continue
+		}
+
+		s.calls++
+		if len(s.responses) == 0 {
+			return Response{Status: http.StatusOK}, s.delay, true
+		}
+		i := s.calls - 1
+		if i >= len(s.responses) {
+			i = len(s.responses) - 1
+		}
+		return s.responses[i], s.delay, true
+	}
+
+	m.unmatched = append(m.unmatched, req.Method+" "+req.URL.RequestURI())
+	return Response{}, 0, false
+}
+
+// WithBody only matches the requests whose body is body.
+func (s *Stub) WithBody(body string) *Stub {
+	return s.WithBodyFunc(func(b []byte) bool {
+		return bytes.Equal(b, []byte(body))
+	})
+}
+
+// WithBodyFunc only matches the requests whose body match returns true for.
+func (s *Stub) WithBodyFunc(match func([]byte) bool) *Stub {
+	s.mock.mu.Lock()
+	defer s.mock.mu.Unlock()
+
+	s.body = match
+	return s
+}
+
+// Reply appends a response of status with body to the script.
+func (s *Stub) Reply(status int, body string) *Stub {
+	return s.Respond(Response{Status: status, Body: []byte(body)})
+}
+
+// Drop appends a dropped connection to the script.
+func (s *Stub) Drop() *Stub {
+	return s.Respond(Response{Drop: true})
+}
+
+func (s *Stub) Respond(resp Response) *Stub {
+	s.mock.mu.Lock()
+	defer s.mock.mu.Unlock()
+
+	s.responses = append(s.responses, resp)
+	return s
+}
+
+// Delay holds every response of s for d, or until the request is canceled.
+func (s *Stub) Delay(d time.Duration) *Stub {
+	s.mock.mu.Lock()
+	defer s.mock.mu.Unlock()
+
+	s.delay = d
+	return s
+}
+
+// Calls is the number of requests s matched.
+func (s *Stub) Calls() int {
+	s.mock.mu.Lock()
+	defer s.mock.mu.Unlock()
+
+	return s.calls
+}
+
+func (s *Stub) match(req *http.Request, body []byte) bool {
+	if len(s.method) > 0 && s.method != req.Method {
+		return false
+	}
+	if s.body != nil && !s.body(body) {
+		return false
+	}
+
+	return matchPath(s.path, req.URL.Path)
+}
+
+func matchPath(pattern, path string) bool {
+	patterns := strings.Split(strings.Trim(pattern, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, p := range patterns {
+		if p == "*" && i == len(patterns)-1 {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if !strings.HasPrefix(p, ":") && p != segments[i] {
+			return false
+		}
+	}
+
+	return len(patterns) == len(segments)
+}
+
+func reply(req *http.Request, resp Response) *http.Response {
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := resp.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}
+}
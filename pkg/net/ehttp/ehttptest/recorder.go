@@ -0,0 +1,183 @@
+package ehttptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/GaVender/era/pkg/redact"
+)
+
+type (
+	Mode int
+
+	// Recorder forwards the requests to upstream and records the exchanges in ModeRecord,
+	// Save writes them to the cassette. In ModeReplay it replies from the cassette without any network,
+	// each exchange once in order, the last matching one is repeated once they run out,
+	// and the requests missing from it get a 501.
+	Recorder struct {
+		path      string
+		mode      Mode
+		upstream  *url.URL
+		transport http.RoundTripper
+		mu        sync.Mutex
+		cassette  Cassette
+		replayed  []bool
+	}
+
+	// Cassette is stored as JSON, the bodies as text. The request headers are left out
+	// and the response headers are masked by redact.Default(), the URIs and bodies are stored as sent,
+	// review a cassette before committing it when they may carry credentials.
+	Cassette struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+
+	Interaction struct {
+		Request  RecordedRequest  `json:"request"`
+		Response RecordedResponse `json:"response"`
+	}
+
+	RecordedRequest struct {
+		Method string `json:"method"`
+		URI    string `json:"uri"`
+		Body   string `json:"body,omitempty"`
+	}
+
+	RecordedResponse struct {
+		Status int         `json:"status"`
+		Header http.Header `json:"header,omitempty"`
+		Body   string      `json:"body,omitempty"`
+	}
+)
+
+const (
+	ModeReplay Mode = iota
+	ModeRecord
+
+	// EnvRecord switches ModeFromEnv to ModeRecord, e.g. ERA_HTTP_RECORD=1 go test ./...
+	EnvRecord = "ERA_HTTP_RECORD"
+)
+
+// ModeFromEnv is ModeRecord when EnvRecord is set, ModeReplay otherwise.
+func ModeFromEnv() Mode {
+	if len(os.Getenv(EnvRecord)) > 0 {
+		return ModeRecord
+	}
+
+	return ModeReplay
+}
+
+// NewRecorder loads the cassette at path in ModeReplay, upstream is only used in ModeRecord.
+func NewRecorder(path string, mode Mode, upstream string) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, transport: http.DefaultTransport}
+
+	if mode == ModeRecord {
+		u, err := url.Parse(upstream)
+		if err != nil {
+			return nil, err
+		}
+		r.upstream = u
+		return r, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &r.cassette); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	r.replayed = make([]bool, len(r.cassette.Interactions))
+
+	return r, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	recorded := RecordedRequest{Method: req.Method, URI: req.URL.RequestURI(), Body: string(body)}
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Scheme = r.upstream.Scheme
+	out.URL.Host = r.upstream.Host
+	out.URL.Path = r.upstream.Path + req.URL.Path
+	out.Host = r.upstream.Host
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  recorded,
+		Response: RecordedResponse{Status: resp.StatusCode, Header: redact.Default().Header(resp.Header), Body: string(respBody)},
+	})
+	r.mu.Unlock()
+
+	return reply(req, Response{Status: resp.StatusCode, Header: resp.Header, Body: respBody}), nil
+}
+
+// Save writes the recorded exchanges to the cassette, it does nothing in ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.path, b, 0644)
+}
+
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i, in := range r.cassette.Interactions {
+		if in.Request != recorded {
+			continue
+		}
+		last = i
+		if !r.replayed[i] {
+			break
+		}
+	}
+	if last < 0 {
+		return reply(req, Response{
+			Status: http.StatusNotImplemented,
+			Body:   []byte(fmt.Sprintf("ehttptest: %s %s not recorded", recorded.Method, recorded.URI)),
+		}), nil
+	}
+
+	r.replayed[last] = true
+	resp := r.cassette.Interactions[last].Response
+	return reply(req, Response{Status: resp.Status, Header: resp.Header, Body: []byte(resp.Body)}), nil
+}
@@ -1,31 +1,189 @@
 package ehttp
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GaVender/cast"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/GaVender/era/pkg/breaker"
+	"github.com/GaVender/era/pkg/net/balancer"
+	"github.com/GaVender/era/pkg/ratelimit"
+)
+
+type (
+	// RouteNormalizer turns the path of an outgoing request into the route label of its metrics,
+	// it must map the ids in the path to a fixed template to keep the number of series bounded.
+	RouteNormalizer func(path string) string
+)
+
+const (
+	labelNone = "none"
+
+	errorTimeout     = "timeout"
+	errorCanceled    = "canceled"
+	errorCircuitOpen = "circuit_open"
+	errorRateLimited = "rate_limited"
+	errorNoEndpoint  = "no_endpoint"
+	errorNetwork     = "network"
+	errorOther       = "other"
+)
 
 var (
 	namespace = "era"
 	subsystem = "http"
 
+	// DefaultDurationBuckets are in seconds, from 5ms to 10s.
+	DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	metricsMu sync.RWMutex
+
 	metricsHttpRequestCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: namespace,
 		Subsystem: subsystem,
 		Name:      "http_request_total",
-		Help:      "total number of http request times",
+		Help:      "total number of http request attempts",
 	}, []string{
-		"url",
+		"host",
+		"route",
+		"method",
+		"status_class",
+		"error",
+		"retry",
 	})
 
-	metricsHttpRequestDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	metricsHttpRequestDurationHistogram = newClientHistogram(DefaultDurationBuckets)
+
+	metricsHttpServerRequestCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: namespace,
 		Subsystem: subsystem,
-		Name:      "http_request_duration",
-		Help:      "duration histogram of http request",
-		Buckets:   []float64{10, 50, 100, 500, 1000, 10000, 50000},
+		Name:      "server_request_total",
+		Help:      "total number of http requests served",
 	}, []string{
-		"url",
+		"method",
+		"route",
+		"status",
 	})
+
+	metricsHttpServerRequestDurationHistogram = newServerHistogram(DefaultDurationBuckets)
+
+	idSegment = regexp.MustCompile(`^(?:\d+|[0-9a-fA-F]{16,}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
 )
 
 func init() {
-	prometheus.MustRegister(metricsHttpRequestCounter, metricsHttpRequestDurationHistogram)
+	prometheus.MustRegister(metricsHttpRequestCounter, metricsHttpRequestDurationHistogram,
+		metricsHttpServerRequestCounter, metricsHttpServerRequestDurationHistogram)
+}
+
+// SetDurationBuckets replaces the buckets of the client and server duration histograms,
+// it is meant to be called once at startup, before any request is observed.
+func SetDurationBuckets(buckets []float64) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	prometheus.Unregister(metricsHttpRequestDurationHistogram)
+	prometheus.Unregister(metricsHttpServerRequestDurationHistogram)
+	metricsHttpRequestDurationHistogram = newClientHistogram(buckets)
+	metricsHttpServerRequestDurationHistogram = newServerHistogram(buckets)
+	prometheus.MustRegister(metricsHttpRequestDurationHistogram, metricsHttpServerRequestDurationHistogram)
+}
+
+// NormalizeRoute is the default RouteNormalizer, it replaces the numeric, hex and uuid segments with ":id".
+func NormalizeRoute(path string) string {
+	if len(path) == 0 {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if idSegment.MatchString(seg) {
+			segments[i] = ":id"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func newClientHistogram(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "http_request_duration_seconds",
+		Help:      "duration histogram of http request",
+		Buckets:   buckets,
+	}, []string{
+		"host",
+		"route",
+		"method",
+		"status_class",
+	})
+}
+
+func newServerHistogram(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "server_request_duration_seconds",
+		Help:      "duration histogram of http requests served",
+		Buckets:   buckets,
+	}, []string{
+		"method",
+		"route",
+	})
+}
+
+func clientHistogram() *prometheus.HistogramVec {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+
+	return metricsHttpRequestDurationHistogram
+}
+
+func serverHistogram() *prometheus.HistogramVec {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+
+	return metricsHttpServerRequestDurationHistogram
+}
+
+// statusClass is 2xx, 4xx... of the response, none when there is no response.
+func statusClass(resp *cast.Response) string {
+	if resp == nil || resp.StatusCode() < 100 {
+		return labelNone
+	}
+
+	return strconv.Itoa(resp.StatusCode()/100) + "xx"
+}
+
+func errorClass(err error) string {
+	if err == nil {
+		return labelNone
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, breaker.ErrCircuitOpen):
+		return errorCircuitOpen
+	case errors.Is(err, ratelimit.ErrLimited):
+		return errorRateLimited
+	case errors.Is(err, balancer.ErrNoEndpoint):
+		return errorNoEndpoint
+	case errors.Is(err, context.Canceled):
+		return errorCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorTimeout
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return errorTimeout
+		}
+		return errorNetwork
+	default:
+		return errorOther
+	}
 }
@@ -0,0 +1,84 @@
+package ehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/GaVender/cast"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/GaVender/era/pkg/breaker"
+	"github.com/GaVender/era/pkg/net/balancer"
+	"github.com/GaVender/era/pkg/ratelimit"
+)
+
+func TestNormalizeRoute(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "", want: "/"},
+		{path: "/users", want: "/users"},
+		{path: "/users/42/orders", want: "/users/:id/orders"},
+		{path: "/orders/5f0c6b1e9d3a4c2b8e7f1a2b", want: "/orders/:id"},
+		{path: "/tokens/123e4567-e89b-12d3-a456-426614174000", want: "/tokens/:id"},
+		{path: "/v2/items", want: "/v2/items"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeRoute(tt.path); got != tt.want {
+			t.Errorf("NormalizeRoute(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: labelNone},
+		{err: context.DeadlineExceeded, want: errorTimeout},
+		{err: &url.Error{Op: "Get", URL: "http://127.0.0.1", Err: context.Canceled}, want: errorCanceled},
+		{err: fmt.Errorf("send: %w", &breaker.Error{Name: "partner"}), want: errorCircuitOpen},
+		{err: &ratelimit.Error{Name: "partner", Key: "api"}, want: errorRateLimited},
+		{err: fmt.Errorf("balancer partner: %w", balancer.ErrNoEndpoint), want: errorNoEndpoint},
+		{err: &url.Error{Op: "Get", URL: "http://127.0.0.1", Err: errors.New("connection refused")}, want: errorNetwork},
+		{err: errors.New("bad template"), want: errorOther},
+	}
+
+	for _, tt := range tests {
+		if got := errorClass(tt.err); got != tt.want {
+			t.Errorf("errorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClientMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(cast.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.WithMonitor(true)
+
+	if _, err := client.Send(context.Background(), client.NewRequest().WithPath("/users/42?token=secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	count := testutil.ToFloat64(metricsHttpRequestCounter.WithLabelValues(host, "/users/:id", http.MethodGet, "5xx", labelNone, "0"))
+	if count != 1 {
+		t.Errorf("request counter = %v", count)
+	}
+}
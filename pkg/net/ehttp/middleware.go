@@ -0,0 +1,251 @@
+package ehttp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/GaVender/era/pkg/log"
+	"github.com/GaVender/era/pkg/opentrace"
+	"github.com/GaVender/era/pkg/redact"
+	"github.com/GaVender/era/utils"
+)
+
+type (
+	Middleware func(http.Handler) http.Handler
+
+	// statusWriter remembers the status and the size of the response for the middleware.
+	statusWriter struct {
+		http.ResponseWriter
+		status int
+		size   int
+	}
+
+	requestIDKey struct{}
+)
+
+const (
+	HeaderRequestID = "X-Request-Id"
+
+	serverOperation = "http server: "
+)
+
+// Chain wraps h with mws, the first one is the outermost.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+
+	return h
+}
+
+// RequestID reuses the X-Request-Id of the request or generates one, echoes it in the response
+// and adds it to the request logger, which is logger when the request carries none.
+func RequestID(logger log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(HeaderRequestID)
+			if len(id) == 0 {
+				id = newRequestID()
+			}
+			w.Header().Set(HeaderRequestID, id)
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = log.IntoContext(ctx, log.FromContextOr(ctx, logger).With(log.String("request_id", id)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFromContext is the id set by the RequestID middleware.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Trace continues the trace of the caller, or starts one, with a server span named after the route template.
+// A nil tracer falls back to opentracing.GlobalTracer().
+func Trace(tracer opentracing.Tracer) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remote, _ := opentrace.ExtractRequest(tracer, r)
+			route := Route(r)
+			sp, ctx := opentrace.StartFrom(r.Context(), tracer, serverOperation+r.Method+" "+route, remote, opentracing.Tags{
+				string(ext.SpanKind):                 ext.SpanKindRPCServerEnum,
+				string(ext.HTTPMethod):               r.Method,
+				string(ext.HTTPUrl):                  r.URL.Path,
+				string(semconv.HTTPRequestMethodKey): r.Method,
+				string(semconv.HTTPRouteKey):         route,
+				string(semconv.URLPathKey):           r.URL.Path,
+			})
+
+			sw := wrap(w)
+			defer func() {
+				status := sw.code()
+				ext.HTTPStatusCode.Set(sp, uint16(status))
+				opentrace.SetAttributes(sp, semconv.HTTPResponseStatusCode(status))
+				if status >= http.StatusInternalServerError {
+					ext.Error.Set(sp, true)
+				}
+				sp.Finish()
+			}()
+
+			next.ServeHTTP(sw, r.WithContext(ctx))
+		})
+	}
+}
+
+// Metrics counts the requests and observes their duration by method, route template and status.
+func Metrics() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			beginTime := time.Now()
+			sw := wrap(w)
+			defer func() {
+				route := Route(r)
+				metricsHttpServerRequestCounter.WithLabelValues(r.Method, route, strconv.Itoa(sw.code())).Inc()
+				serverHistogram().WithLabelValues(r.Method, route).Observe(time.Now().Sub(beginTime).Seconds())
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+// AccessLog logs every request through the request logger, or logger when it carries none,
+// the server errors at error level. A nil redactor falls back to redact.Default().
+func AccessLog(logger log.Logger, redactor *redact.Redactor) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			beginTime := time.Now()
+			sw := wrap(w)
+			defer func() {
+				fields := []log.Field{
+					log.String("method", r.Method),
+					log.String("route", Route(r)),
+					log.String("path", r.URL.Path),
+					log.String("query", redact.Or(redactor).Query(r.URL.Query()).Encode()),
+					log.Int("status", sw.code()),
+					log.Int("size", sw.size),
+					log.String("remote", r.RemoteAddr),
+					log.Duration("duration", time.Now().Sub(beginTime)),
+				}
+
+				l := log.FromContextOr(r.Context(), logger).Named("ehttp")
+				if sw.code() >= http.StatusInternalServerError {
+					l.ContextErrorField(r.Context(), "http access", fields...)
+				} else {
+					l.ContextInfoField(r.Context(), "http access", fields...)
+				}
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+// Recovery turns a panic of the handler into a 500 and logs it with its stack.
+// http.ErrAbortHandler is panicked again so net/http aborts the response.
+func Recovery(logger log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := wrap(w)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				stack := utils.Stack(3)
+				log.FromContextOr(r.Context(), logger).Named("ehttp").ContextErrorField(r.Context(), "http panic",
+					log.String("method", r.Method),
+					log.String("route", Route(r)),
+					log.String("panic", fmt.Sprint(rec)),
+					log.String("stack", stack.String()),
+				)
+				if sp := opentracing.SpanFromContext(r.Context()); sp != nil {
+					opentrace.SetError(sp, fmt.Errorf("panic: %v", rec))
+				}
+
+				if sw.status == 0 {
+					http.Error(sw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+// wrap reuses w when it is already a statusWriter, so the middleware share one.
+func wrap(w http.ResponseWriter) *statusWriter {
+	if sw, ok := w.(*statusWriter); ok {
+		return sw
+	}
+
+	return &statusWriter{ResponseWriter: w}
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// code is the status sent, net/http sends 200 when the handler writes nothing.
+func (w *statusWriter) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ehttp: response writer is not a hijacker")
+	}
+
+	return h.Hijack()
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(b)
+}
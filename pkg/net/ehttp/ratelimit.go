@@ -0,0 +1,53 @@
+package ehttp
+
+import (
+	"context"
+
+	"github.com/GaVender/era/pkg/ratelimit"
+)
+
+type (
+	// RateLimit takes a permit of Limiter before every attempt of a request.
+	// The permits are shared under Key, or taken per target host when PerHost is set.
+	RateLimit struct {
+		Limiter ratelimit.Limiter
+		Key     string
+		PerHost bool
+		Mode    ratelimit.Mode
+	}
+)
+
+// WithRateLimit throttles every request sent by c, requests over the limit wait
+// or fail with ratelimit.ErrLimited depending on the mode of rl.
+func (c *Client) WithRateLimit(rl RateLimit) *Client {
+	c.rateLimit = &rl
+	return c
+}
+
+// WithHostRateLimit throttles the requests sent by c to host, as in its URL, e.g. "api.partner.com",
+// instead of the client's RateLimit.
+func (c *Client) WithHostRateLimit(host string, rl RateLimit) *Client {
+	if c.hostRateLimits == nil {
+		c.hostRateLimits = make(map[string]*RateLimit)
+	}
+	c.hostRateLimits[host] = &rl
+	return c
+}
+
+// acquire takes a permit for a request to t, if c is rate limited.
+func (c *Client) acquire(ctx context.Context, t target) error {
+	rl, ok := c.hostRateLimits[t.host]
+	if !ok {
+		rl = c.rateLimit
+	}
+	if rl == nil || rl.Limiter == nil {
+		return nil
+	}
+
+	key := rl.Key
+	if rl.PerHost || ok && key == "" {
+		key = t.host
+	}
+
+	return ratelimit.Acquire(ctx, rl.Limiter, key, rl.Mode)
+}
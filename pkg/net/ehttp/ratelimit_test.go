@@ -0,0 +1,63 @@
+package ehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/GaVender/cast"
+
+	"github.com/GaVender/era/pkg/ratelimit"
+)
+
+func TestClientRateLimit(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	tests := []struct {
+		name      string
+		limit     func(c *Client)
+		wantCalls int32
+	}{
+		{name: "client", limit: func(c *Client) {
+			c.WithRateLimit(RateLimit{Limiter: ratelimit.NewTokenBucket("client", 1, 1), Mode: ratelimit.ModeFailFast})
+		}, wantCalls: 1},
+		{name: "host", limit: func(c *Client) {
+			c.WithRateLimit(RateLimit{Limiter: ratelimit.NewTokenBucket("other", 1, 2)}).
+				WithHostRateLimit(host, RateLimit{Limiter: ratelimit.NewTokenBucket("host", 1, 1), Mode: ratelimit.ModeFailFast})
+		}, wantCalls: 1},
+		{name: "other host", limit: func(c *Client) {
+			c.WithHostRateLimit("api.partner.com", RateLimit{Limiter: ratelimit.NewTokenBucket("partner", 1, 1), Mode: ratelimit.ModeFailFast})
+		}, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			client, err := NewClient(cast.WithBaseURL(srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.limit(client)
+
+			for i := 0; i < 2; i++ {
+				_, err := client.Send(context.Background(), client.NewRequest())
+				if limited := i >= int(tt.wantCalls); limited != errors.Is(err, ratelimit.ErrLimited) {
+					t.Errorf("request %d: err = %v", i, err)
+				}
+			}
+
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package ehttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/GaVender/cast"
+
+	"github.com/GaVender/era/pkg/redact"
+)
+
+type (
+	// StatusError is returned by Send for the non-2xx responses of the clients WithStatusError,
+	// along with the response. Header is redacted, Body is redacted and truncated to the log capture limit.
+	StatusError struct {
+		Status int
+		Header http.Header
+		Body   string
+	}
+)
+
+const (
+	// DefaultTagBodyLimit and DefaultLogBodyLimit are the bytes of a body captured in span tags and logs.
+	DefaultTagBodyLimit = 4 << 10
+	DefaultLogBodyLimit = 1 << 10
+)
+
+var (
+	ErrStatus = errors.New("http status")
+)
+
+// WithCaptureLimits keeps at most tag bytes of the request and response bodies in span tags
+// and log bytes in logs and StatusError, a negative limit leaves them out.
+func (c *Client) WithCaptureLimits(tag, log int) *Client {
+	c.tagBodyLimit = tag
+	c.logBodyLimit = log
+	return c
+}
+
+// WithStatusError makes Send fail with a *StatusError on the non-2xx responses, after any retry.
+func (c *Client) WithStatusError(able bool) *Client {
+	c.failOnStatus = able
+	return c
+}
+
+func (e *StatusError) Error() string {
+	return ErrStatus.Error() + " " + strconv.Itoa(e.Status) + " " + http.StatusText(e.Status)
+}
+
+func (e *StatusError) Is(target error) bool {
+	return target == ErrStatus
+}
+
+// statusError is the *StatusError of resp, nil for a 2xx or a missing response.
+func (c *Client) statusError(resp *cast.Response) error {
+	if resp == nil || resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
+		return nil
+	}
+
+	r := redact.Or(c.redactor)
+	return &StatusError{
+		Status: resp.StatusCode(),
+		Header: r.Header(resp.Header()),
+		Body:   capture(r, resp.Body(), c.logBodyLimit),
+	}
+}
+
+// capture is body redacted and truncated to limit bytes, empty when limit is negative.
+// A binary body is only captured by its size.
+func capture(r *redact.Redactor, body []byte, limit int) string {
+	if limit < 0 || len(body) == 0 {
+		return ""
+	}
+	if !utf8.Valid(body) {
+		return "(" + strconv.Itoa(len(body)) + " bytes binary)"
+	}
+
+	s := r.JSON(body)
+	if len(s) <= limit {
+		return s
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "...(" + strconv.Itoa(len(s)-cut) + " bytes truncated)"
+}
@@ -0,0 +1,113 @@
+package ehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GaVender/cast"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/GaVender/era/pkg/breaker"
+)
+
+func TestClientSendFailures(t *testing.T) {
+	large := strings.Repeat("x", 100)
+
+	tests := []struct {
+		name         string
+		status       int
+		down         bool
+		statusError  bool
+		wantErr      error
+		wantSpanErr  bool
+		wantResponse string
+	}{
+		{name: "network error", down: true, wantSpanErr: true},
+		{name: "server error", status: http.StatusBadGateway, wantSpanErr: true, wantResponse: large[:16] + "...(84 bytes truncated)"},
+		{name: "client error", status: http.StatusNotFound, wantResponse: large[:16] + "...(84 bytes truncated)"},
+		{name: "status error", status: http.StatusNotFound, statusError: true, wantErr: ErrStatus, wantSpanErr: true, wantResponse: large[:16] + "...(84 bytes truncated)"},
+		{name: "ok with status error", status: http.StatusOK, statusError: true, wantResponse: large[:16] + "...(84 bytes truncated)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Reason", "test")
+				w.Header().Set("Set-Cookie", "session=s3cr3t")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(large))
+			}))
+			if tt.down {
+				srv.Close()
+			} else {
+				defer srv.Close()
+			}
+
+			client, err := NewClient(cast.WithBaseURL(srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracer := mocktracer.New()
+			client.WithTracer(tracer).WithCaptureLimits(16, 8).WithStatusError(tt.statusError)
+
+			_, err = client.Send(context.Background(), client.NewRequest())
+			switch {
+			case tt.down && err == nil:
+				t.Fatal("Send() succeeded against a closed server")
+			case tt.wantErr != nil:
+				var e *StatusError
+				if !errors.Is(err, tt.wantErr) || !errors.As(err, &e) || e.Status != tt.status ||
+					e.Header.Get("X-Reason") != "test" || strings.Contains(e.Header.Get("Set-Cookie"), "s3cr3t") || e.Body != large[:8]+"...(92 bytes truncated)" {
+					t.Errorf("err = %#v", err)
+				}
+			case !tt.down && err != nil:
+				t.Fatal(err)
+			}
+
+			spans := tracer.FinishedSpans()
+			if len(spans) != 1 {
+				t.Fatalf("%d spans", len(spans))
+			}
+			tags := spans[0].Tags()
+			if spanErr, _ := tags["error"].(bool); spanErr != tt.wantSpanErr {
+				t.Errorf("span error = %v", tags["error"])
+			}
+			if got, _ := tags["response"].(string); got != tt.wantResponse {
+				t.Errorf("response tag = %q", got)
+			}
+			if _, ok := tags[string(semconv.ErrorTypeKey)]; ok != (err != nil) {
+				t.Errorf("error.type = %v with err %v", tags[string(semconv.ErrorTypeKey)], err)
+			}
+		})
+	}
+}
+
+func TestClientBreakerCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(cast.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := breaker.New("canceled", breaker.Config{MinRequests: 1})
+	client.WithBreaker(b)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.Send(ctx, client.NewRequest()); err == nil {
+		t.Fatal("Send() succeeded past the deadline")
+	}
+
+	if b.State() != breaker.StateClosed {
+		t.Errorf("breaker %s after the caller gave up", b.State())
+	}
+}
@@ -0,0 +1,165 @@
+package ehttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GaVender/cast"
+
+	"github.com/GaVender/era/pkg/backoff"
+)
+
+type (
+	// RetryPolicy retries the network errors and the Statuses, DefaultRetryStatuses when empty,
+	// waiting the backoff of Policy or the Retry-After of the response, whichever is longer.
+	// A Retry-After beyond MaxRetryAfter, when set, is not waited for.
+	// Only idempotent methods, or requests with an Idempotency-Key header, are retried unless NonIdempotent is set.
+	RetryPolicy struct {
+		backoff.Policy
+		Statuses      []int
+		NonIdempotent bool
+		MaxRetryAfter time.Duration
+	}
+
+	retryKey struct{}
+)
+
+const (
+	HeaderRetryAfter     = "Retry-After"
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+var (
+	DefaultRetryStatuses = []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+
+	idempotentMethods = map[string]bool{
+		http.MethodGet:     true,
+		http.MethodHead:    true,
+		http.MethodOptions: true,
+		http.MethodTrace:   true,
+		http.MethodPut:     true,
+		http.MethodDelete:  true,
+	}
+)
+
+// WithRetry retries every request sent by c with policy.
+func (c *Client) WithRetry(policy RetryPolicy) *Client {
+	c.retry = &policy
+	return c
+}
+
+// WithRequestRetry overrides the retry policy of the client for the requests sent with ctx.
+func WithRequestRetry(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryKey{}, &policy)
+}
+
+// retryPolicy is the policy of the request, a single attempt when it is not to be retried.
+func (c *Client) retryPolicy(ctx context.Context, request *cast.Request) RetryPolicy {
+	policy, ok := ctx.Value(retryKey{}).(*RetryPolicy)
+	if !ok {
+		policy = c.retry
+	}
+
+	if policy == nil || !policy.NonIdempotent && !idempotent(request) {
+		return RetryPolicy{Policy: backoff.Policy{Attempts: 1}}
+	}
+
+	return *policy
+}
+
+// next tells whether the attempt is to be retried and how long to wait before.
+func (p RetryPolicy) next(ctx context.Context, attempt int, resp *cast.Response, err error) (time.Duration, bool) {
+	if attempt >= p.Attempts || ctx.Err() != nil {
+		return 0, false
+	}
+
+	wait := p.Backoff(attempt)
+	switch {
+	case err != nil:
+		var netErr net.Error
+		if !errors.As(err, &netErr) && !cast.ShouldRetry(err) {
+			return 0, false
+		}
+	case resp != nil && p.retryStatus(resp.StatusCode()):
+		if after, ok := retryAfter(resp.Header().Get(HeaderRetryAfter), time.Now()); ok {
+			if p.MaxRetryAfter > 0 && after > p.MaxRetryAfter {
+				return 0, false
+			}
+			if after > wait {
+				wait = after
+			}
+		}
+	default:
+		return 0, false
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false
+	}
+
+	return wait, true
+}
+
+func (p RetryPolicy) retryStatus(status int) bool {
+	statuses := p.Statuses
+	if len(statuses) == 0 {
+		statuses = DefaultRetryStatuses
+	}
+
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func idempotent(request *cast.Request) bool {
+	return idempotentMethods[request.GetMethod()] || len(request.GetHeader().Get(HeaderIdempotencyKey)) > 0
+}
+
+// retryAfter parses a Retry-After header, either delay seconds or an HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+
+	return 0, true
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,104 @@
+package ehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GaVender/cast"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/GaVender/era/pkg/backoff"
+)
+
+func TestClientRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		policy       RetryPolicy
+		failures     int32
+		retryAfter   string
+		wantCalls    int32
+		wantStatus   int
+		wantAttempts int
+	}{
+		{name: "recovers", method: http.MethodGet, policy: RetryPolicy{Policy: backoff.Policy{Attempts: 3}}, failures: 2, wantCalls: 3, wantStatus: http.StatusOK, wantAttempts: 3},
+		{name: "runs out", method: http.MethodGet, policy: RetryPolicy{Policy: backoff.Policy{Attempts: 2}}, failures: 5, wantCalls: 2, wantStatus: http.StatusServiceUnavailable, wantAttempts: 2},
+		{name: "not idempotent", method: http.MethodPost, policy: RetryPolicy{Policy: backoff.Policy{Attempts: 3}}, failures: 1, wantCalls: 1, wantStatus: http.StatusServiceUnavailable},
+		{name: "non idempotent allowed", method: http.MethodPost, policy: RetryPolicy{Policy: backoff.Policy{Attempts: 3}, NonIdempotent: true}, failures: 1, wantCalls: 2, wantStatus: http.StatusOK, wantAttempts: 2},
+		{name: "status not retried", method: http.MethodGet, policy: RetryPolicy{Policy: backoff.Policy{Attempts: 3}, Statuses: []int{http.StatusBadGateway}}, failures: 1, wantCalls: 1, wantStatus: http.StatusServiceUnavailable, wantAttempts: 1},
+		{name: "retry after too long", method: http.MethodGet, policy: RetryPolicy{Policy: backoff.Policy{Attempts: 3}, MaxRetryAfter: time.Second}, failures: 1, retryAfter: "120", wantCalls: 1, wantStatus: http.StatusServiceUnavailable, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= tt.failures {
+					if len(tt.retryAfter) > 0 {
+						w.Header().Set(HeaderRetryAfter, tt.retryAfter)
+					}
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+
+			client, err := NewClient(cast.WithBaseURL(srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracer := mocktracer.New()
+			client.WithTracer(tracer).WithRetry(tt.policy)
+
+			resp, err := client.Send(context.Background(), client.NewRequest().Method(tt.method))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if resp.StatusCode() != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode(), tt.wantStatus)
+			}
+
+			attempts := 0
+			for _, sp := range tracer.FinishedSpans() {
+				if sp.Tag("http.request.resend_count") != nil {
+					attempts++
+				}
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempt spans = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{value: ""},
+		{value: "3", want: 3 * time.Second, wantOk: true},
+		{value: "-1"},
+		{value: "Wed, 01 Apr 2020 12:00:30 GMT", want: 30 * time.Second, wantOk: true},
+		{value: "Wed, 01 Apr 2020 11:00:00 GMT", wantOk: true},
+		{value: "soon"},
+	}
+
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
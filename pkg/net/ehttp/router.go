@@ -0,0 +1,191 @@
+package ehttp
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type (
+	// Router matches the method and the path of a request against route templates,
+	// where a ":name" segment matches one segment and a trailing "*name" segment matches the rest of the path.
+	Router struct {
+		routes           []*route
+		NotFound         http.Handler
+		MethodNotAllowed http.Handler
+	}
+
+	route struct {
+		method   string
+		pattern  string
+		segments []string
+		score    int
+		handler  http.Handler
+	}
+
+	match struct {
+		route   *route
+		params  map[string]string
+		allowed []string
+	}
+
+	matchKey struct{}
+)
+
+const (
+	// RouteNotFound is the route template of the requests no route matches.
+	RouteNotFound = "unmatched"
+)
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers h for method and pattern, an empty method matches every method.
+// It panics on an invalid pattern like http.ServeMux does.
+func (r *Router) Handle(method, pattern string, h http.Handler) {
+	if !strings.HasPrefix(pattern, "/") {
+		panic("ehttp: pattern " + pattern + " must begin with /")
+	}
+
+	rt := &route{method: strings.ToUpper(method), pattern: pattern, segments: split(pattern), handler: h}
+	for i, seg := range rt.segments {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			if i != len(rt.segments)-1 {
+				panic("ehttp: pattern " + pattern + " has * before its last segment")
+			}
+			rt.score++
+		case strings.HasPrefix(seg, ":"):
+			rt.score += 2
+		default:
+			rt.score += 3
+		}
+	}
+
+	r.routes = append(r.routes, rt)
+}
+
+func (r *Router) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	r.Handle(method, pattern, http.HandlerFunc(f))
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m := r.match(req.Method, req.URL.Path)
+	r.dispatch(w, req.WithContext(context.WithValue(req.Context(), matchKey{}, m)))
+}
+
+// Param is the value of the ":name" or "*name" segment of the route matched by req.
+func Param(req *http.Request, name string) string {
+	if m, ok := req.Context().Value(matchKey{}).(*match); ok {
+		return m.params[name]
+	}
+
+	return ""
+}
+
+// Route is the template of the route matched by req, or RouteNotFound.
+func Route(req *http.Request) string {
+	if m, ok := req.Context().Value(matchKey{}).(*match); ok && m.route != nil {
+		return m.route.pattern
+	}
+
+	return RouteNotFound
+}
+
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request) {
+	m, _ := req.Context().Value(matchKey{}).(*match)
+	switch {
+	case m != nil && m.route != nil:
+		m.route.handler.ServeHTTP(w, req)
+	case m != nil && len(m.allowed) > 0:
+		w.Header().Set("Allow", strings.Join(m.allowed, ", "))
+		if r.MethodNotAllowed != nil {
+			r.MethodNotAllowed.ServeHTTP(w, req)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	case r.NotFound != nil:
+		r.NotFound.ServeHTTP(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// match picks the most specific route, literal segments before parameters before catch-alls,
+// the first registered one wins a tie.
+func (r *Router) match(method, path string) *match {
+	var (
+		best    *match
+		allowed = map[string]bool{}
+		parts   = split(path)
+	)
+
+	for _, rt := range r.routes {
+		params, ok := rt.match(parts)
+		if !ok {
+			continue
+		}
+
+		if len(rt.method) > 0 && rt.method != method {
+			allowed[rt.method] = true
+			continue
+		}
+
+		if best == nil || rt.score > best.route.score {
+			best = &match{route: rt, params: params}
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+
+	m := &match{}
+	for method := range allowed {
+		m.allowed = append(m.allowed, method)
+	}
+	sort.Strings(m.allowed)
+
+	return m
+}
+
+func (rt *route) match(parts []string) (map[string]string, bool) {
+	var params map[string]string
+	for i, seg := range rt.segments {
+		if strings.HasPrefix(seg, "*") {
+			params = setParam(params, seg[1:], strings.Join(parts[i:], "/"))
+			return params, true
+		}
+
+		if i >= len(parts) {
+			return nil, false
+		}
+
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			if len(parts[i]) == 0 {
+				return nil, false
+			}
+			params = setParam(params, seg[1:], parts[i])
+		case seg != parts[i]:
+			return nil, false
+		}
+	}
+
+	return params, len(parts) == len(rt.segments)
+}
+
+func setParam(params map[string]string, name, value string) map[string]string {
+	if params == nil {
+		params = make(map[string]string)
+	}
+	params[name] = value
+
+	return params
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
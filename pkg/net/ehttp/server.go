@@ -0,0 +1,188 @@
+package ehttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/GaVender/era/pkg/app"
+	"github.com/GaVender/era/pkg/log"
+	"github.com/GaVender/era/pkg/redact"
+)
+
+type (
+	ServerConfig struct {
+		Addr            string
+		ReadTimeout     time.Duration
+		WriteTimeout    time.Duration
+		IdleTimeout     time.Duration
+		ShutdownTimeout time.Duration
+	}
+
+	// Server is a Router behind the era middleware, outermost first:
+	// request id, tracing, metrics, access log, panic recovery and then the middleware of Use.
+	Server struct {
+		*Router
+		cfg         ServerConfig
+		logger      log.Logger
+		tracer      opentracing.Tracer
+		redactor    *redact.Redactor
+		ableMonitor bool
+		middlewares []Middleware
+		once        sync.Once
+		handler     http.Handler
+		srv         *http.Server
+		listener    net.Listener
+	}
+
+	ServerOption func(*Server)
+)
+
+const (
+	defaultServerAddr      = ":8080"
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+var (
+	ErrServerNotStarted = errors.New("http server not started")
+)
+
+func NewServer(cfg ServerConfig, opts ...ServerOption) *Server {
+	if len(cfg.Addr) == 0 {
+		cfg.Addr = defaultServerAddr
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	s := &Server{Router: NewRouter(), cfg: cfg, ableMonitor: true}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.logger == nil {
+		s.logger = log.NullLogger{}
+	}
+
+	return s
+}
+
+func WithLogger(logger log.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithTracer traces the requests with tracer instead of opentracing.GlobalTracer().
+func WithTracer(tracer opentracing.Tracer) ServerOption {
+	return func(s *Server) {
+		s.tracer = tracer
+	}
+}
+
+// WithRedactor masks the logged query strings with redactor instead of redact.Default().
+func WithRedactor(redactor *redact.Redactor) ServerOption {
+	return func(s *Server) {
+		s.redactor = redactor
+	}
+}
+
+// WithMonitor turns the request metrics on or off, they are on by default.
+func WithMonitor(able bool) ServerOption {
+	return func(s *Server) {
+		s.ableMonitor = able
+	}
+}
+
+func WithMiddleware(mws ...Middleware) ServerOption {
+	return func(s *Server) {
+		s.middlewares = append(s.middlewares, mws...)
+	}
+}
+
+// Use appends mws to the middleware chain, it has no effect once the server has served a request.
+func (s *Server) Use(mws ...Middleware) {
+	s.middlewares = append(s.middlewares, mws...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.once.Do(func() {
+		mws := []Middleware{RequestID(s.logger), Trace(s.tracer)}
+		if s.ableMonitor {
+			mws = append(mws, Metrics())
+		}
+		mws = append(mws, AccessLog(s.logger, s.redactor), Recovery(s.logger))
+		s.handler = Chain(http.HandlerFunc(s.Router.dispatch), append(mws, s.middlewares...)...)
+	})
+
+	// the route is matched first so that every middleware knows its template
+	m := s.Router.match(r.Method, r.URL.Path)
+	s.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), matchKey{}, m)))
+}
+
+// Start listens on the configured address, a port already in use fails here rather than in Run.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	s.listener = listener
+	s.srv = &http.Server{
+		Handler:      s,
+		ReadTimeout:  s.cfg.ReadTimeout,
+		WriteTimeout: s.cfg.WriteTimeout,
+		IdleTimeout:  s.cfg.IdleTimeout,
+	}
+
+	return nil
+}
+
+// Run serves until Stop.
+func (s *Server) Run(ctx context.Context) error {
+	if s.srv == nil {
+		return ErrServerNotStarted
+	}
+
+	s.logger.Infof("http server listening on %s", s.listener.Addr().String())
+	if err := s.srv.Serve(s.listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// Stop stops accepting connections and waits for the requests in flight until ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.srv == nil {
+		return nil
+	}
+
+	return s.srv.Shutdown(ctx)
+}
+
+// Addr is the address listened on, which tells the port picked for ":0".
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.cfg.Addr
+	}
+
+	return s.listener.Addr().String()
+}
+
+// Component runs the server within the app lifecycle, shut down within ShutdownTimeout.
+func (s *Server) Component(name string, dependsOn ...string) app.Component {
+	return app.Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start:     s.Start,
+		Run:       s.Run,
+		Stop:      s.Stop,
+		Timeout:   s.cfg.ShutdownTimeout,
+	}
+}
@@ -0,0 +1,133 @@
+package ehttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/GaVender/era/pkg/log"
+)
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantRoute  string
+	}{
+		{name: "literal", method: http.MethodGet, path: "/users/me", wantStatus: http.StatusOK, wantBody: "me", wantRoute: "/users/me"},
+		{name: "param", method: http.MethodGet, path: "/users/42", wantStatus: http.StatusOK, wantBody: "user 42", wantRoute: "/users/:id"},
+		{name: "catch all", method: http.MethodGet, path: "/static/css/era.css", wantStatus: http.StatusOK, wantBody: "css/era.css", wantRoute: "/static/*file"},
+		{name: "method not allowed", method: http.MethodDelete, path: "/users/42", wantStatus: http.StatusMethodNotAllowed, wantRoute: RouteNotFound},
+		{name: "not found", method: http.MethodGet, path: "/orders/42", wantStatus: http.StatusNotFound, wantRoute: RouteNotFound},
+		{name: "panic", method: http.MethodGet, path: "/panic", wantStatus: http.StatusInternalServerError, wantRoute: "/panic"},
+	}
+
+	logger := log.NewRecorder()
+	tracer := mocktracer.New()
+	s := NewServer(ServerConfig{}, WithLogger(logger), WithTracer(tracer))
+	s.HandleFunc(http.MethodGet, "/users/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("me"))
+	})
+	s.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user " + Param(r, "id")))
+	})
+	s.HandleFunc(http.MethodGet, "/static/*file", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Param(r, "file")))
+	})
+	s.HandleFunc(http.MethodGet, "/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.Reset()
+			tracer.Reset()
+
+			counter := metricsHttpServerRequestCounter.WithLabelValues(tt.method, tt.wantRoute, strconv.Itoa(tt.wantStatus))
+			before := testutil.ToFloat64(counter)
+
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if len(tt.wantBody) > 0 && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if len(w.Header().Get(HeaderRequestID)) == 0 {
+				t.Error("no request id")
+			}
+
+			level := log.LevelInfo
+			if tt.wantStatus >= http.StatusInternalServerError {
+				level = log.LevelError
+			}
+			logger.AssertContains(t, level, "http access",
+				log.String("route", tt.wantRoute), log.Int("status", tt.wantStatus))
+
+			spans := tracer.FinishedSpans()
+			if len(spans) != 1 || spans[0].OperationName != serverOperation+tt.method+" "+tt.wantRoute {
+				t.Errorf("spans = %v", spans)
+			}
+
+			if grown := testutil.ToFloat64(counter) - before; grown != 1 {
+				t.Errorf("request counter grew by %v", grown)
+			}
+		})
+	}
+}
+
+func TestServerTrace(t *testing.T) {
+	tracer := mocktracer.New()
+	s := NewServer(ServerConfig{Addr: "127.0.0.1:0"}, WithTracer(tracer))
+	s.HandleFunc(http.MethodGet, "/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RequestIDFromContext(r.Context())))
+	})
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+
+	caller := tracer.StartSpan("caller")
+	req, _ := http.NewRequest(http.MethodGet, "http://"+s.Addr()+"/ping", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	tracer.Inject(caller.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "req-1" {
+		t.Errorf("request id = %q", body)
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("run = %v", err)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].ParentID != caller.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("server span doesn't continue the caller trace: %v", spans)
+	}
+}
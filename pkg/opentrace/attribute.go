@@ -0,0 +1,43 @@
+package opentrace
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// SetAttributes tags sp with OpenTelemetry attributes, e.g. semconv.DBSystemRedis,
+// the bridge turns them back into the same attributes on the exported span.
+func SetAttributes(sp opentracing.Span, attrs ...attribute.KeyValue) {
+	for _, kv := range attrs {
+		sp.SetTag(string(kv.Key), kv.Value.AsInterface())
+	}
+}
+
+// SetError marks sp failed and records err as an error event.
+func SetError(sp opentracing.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	ext.LogError(sp, err)
+}
+
+// ServerAttributes splits addr into server.address and server.port.
+func ServerAttributes(addr string) []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return []attribute.KeyValue{semconv.ServerAddress(addr)}
+	}
+
+	attrs := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.ServerPort(p))
+	}
+
+	return attrs
+}
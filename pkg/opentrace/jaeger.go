@@ -1,25 +1,78 @@
 package opentrace
 
 import (
+	"errors"
+
 	"github.com/opentracing/opentracing-go"
 	jaegercfg "github.com/uber/jaeger-client-go/config"
 
+	"github.com/GaVender/era/pkg/errs"
 	"github.com/GaVender/era/pkg/log"
 )
 
-func NewTracer(project string, logger log.Logger, cfg jaegercfg.Configuration) (opentracing.Tracer, func()) {
+type (
+	// Error is returned with Kind ErrInit or ErrClose.
+	Error = errs.Error
+
+	TracerOption func(*tracerOptions)
+
+	tracerOptions struct {
+		sampling *SamplingConfig
+	}
+)
+
+var (
+	ErrInit  = errors.New("tracer init")
+	ErrClose = errors.New("tracer close")
+)
+
+// WithSampling replaces the sampler of the jaeger configuration, e.g. WithSampling(EnvSampling()).
+func WithSampling(sampling SamplingConfig) TracerOption {
+	return func(o *tracerOptions) {
+		o.sampling = &sampling
+	}
+}
+
+// NewTracer panics when the tracer can't be built, its closer logs a close error.
+func NewTracer(project string, logger log.Logger, cfg jaegercfg.Configuration, opt ...TracerOption) (opentracing.Tracer, func()) {
+	tracer, closer, err := NewTracerE(project, logger, cfg, opt...)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return tracer, func() {
+		if err := closer(); err != nil {
+			logger.Errorf(err.Error())
+		}
+	}
+}
+
+func NewTracerE(project string, logger log.Logger, cfg jaegercfg.Configuration, opt ...TracerOption) (opentracing.Tracer, func() error, error) {
+	var o tracerOptions
+	for _, f := range opt {
+		f(&o)
+	}
+
+	options := []jaegercfg.Option{jaegercfg.Logger(logger)}
+	if o.sampling != nil {
+		sampler, err := NewSampler(*o.sampling)
+		if err != nil {
+			return nil, nil, &Error{Kind: ErrInit, Err: err}
+		}
+		options = append(options, jaegercfg.Sampler(sampler))
+	}
+
 	cfg.ServiceName = project
-	tracer, closer, err := cfg.NewTracer(
-		jaegercfg.Logger(logger),
-	)
+	tracer, closer, err := cfg.NewTracer(options...)
 	if err != nil {
-		panic("tracer init: " + err.Error())
+		return nil, nil, &Error{Kind: ErrInit, Err: err}
 	}
 
 	opentracing.SetGlobalTracer(tracer)
-	return tracer, func() {
+	return tracer, func() error {
 		if err := closer.Close(); err != nil {
-			panic("tracer close: " + err.Error())
+			return &Error{Kind: ErrClose, Err: err}
 		}
-	}
+		return nil
+	}, nil
 }
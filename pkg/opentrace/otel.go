@@ -0,0 +1,174 @@
+package opentrace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/GaVender/era/pkg/log"
+)
+
+type (
+	// OTelConfig exports to an OTLP collector, an empty Endpoint falls back to OTEL_EXPORTER_OTLP_ENDPOINT
+	// and then to localhost:4318 over HTTP or localhost:4317 over gRPC.
+	OTelConfig struct {
+		Endpoint    string
+		Protocol    string
+		Insecure    bool
+		Headers     map[string]string
+		Attributes  map[string]string
+		SampleRatio float64
+	}
+)
+
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+
+	ExtractorOTel = "otel"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+var (
+	ErrProtocol = errors.New("unsupported otlp protocol")
+)
+
+func init() {
+	log.RegisterTraceExtractor(ExtractorOTel, otelExtractor)
+}
+
+// NewOTelTracer panics when the tracer can't be built, its closer logs a failed flush.
+func NewOTelTracer(project string, logger log.Logger, cfg OTelConfig) (opentracing.Tracer, func()) {
+	if logger == nil {
+		logger = log.NullLogger{}
+	}
+
+	tracer, closer, err := NewOTelTracerE(project, logger, cfg)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return tracer, func() {
+		if err := closer(); err != nil {
+			logger.Errorf(err.Error())
+		}
+	}
+}
+
+// NewOTelTracerE sets up the global OpenTelemetry tracer provider and returns the opentracing bridge to it,
+// so the WithTracer options of the era clients keep working and their spans are exported over OTLP.
+func NewOTelTracerE(project string, logger log.Logger, cfg OTelConfig) (opentracing.Tracer, func() error, error) {
+	if logger == nil {
+		logger = log.NullLogger{}
+	}
+
+	ctx := context.Background()
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, nil, &Error{Kind: ErrInit, Err: err}
+	}
+
+	attrs := []attribute.KeyValue{semconv.ServiceName(project)}
+	for k, v := range cfg.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(attrs...),
+	)
+	if err != nil {
+		return nil, nil, &Error{Kind: ErrInit, Err: err}
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	bridge, wrapper := otbridge.NewTracerPair(provider.Tracer(project))
+	bridge.SetTextMapPropagator(propagator)
+	bridge.SetWarningHandler(func(msg string) {
+		logger.Warnf("otel bridge: %s", msg)
+	})
+
+	otel.SetTracerProvider(wrapper)
+	otel.SetTextMapPropagator(propagator)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Errorf("otel: %s", err.Error())
+	}))
+	opentracing.SetGlobalTracer(bridge)
+
+	return bridge, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := provider.Shutdown(ctx); err != nil {
+			return &Error{Kind: ErrClose, Err: err}
+		}
+		return nil
+	}, nil
+}
+
+func newExporter(ctx context.Context, cfg OTelConfig) (*otlptrace.Exporter, error) {
+	switch cfg.Protocol {
+	case "", ProtocolHTTP:
+		var opts []otlptracehttp.Option
+		if len(cfg.Endpoint) > 0 {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ProtocolGRPC:
+		var opts []otlptracegrpc.Option
+		if len(cfg.Endpoint) > 0 {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, ErrProtocol
+	}
+}
+
+func otelExtractor(ctx context.Context) (log.TraceInfo, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return log.TraceInfo{}, false
+	}
+
+	return log.TraceInfo{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Sampled: sc.IsSampled()}, true
+}
@@ -0,0 +1,58 @@
+package opentrace
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/GaVender/era/pkg/log"
+)
+
+func TestNewOTelTracer(t *testing.T) {
+	var exported int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			atomic.AddInt32(&exported, 1)
+		}
+	}))
+	defer collector.Close()
+
+	tracer, closer, err := NewOTelTracerE("era", nil, OTelConfig{
+		Endpoint: strings.TrimPrefix(collector.URL, "http://"),
+		Insecure: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sp := tracer.StartSpan("redis: get")
+	SetAttributes(sp, semconv.DBSystemRedis, semconv.DBRedisDBIndex(0))
+	SetError(sp, errors.New("i/o timeout"))
+
+	ctx := opentracing.ContextWithSpan(context.Background(), sp)
+	info, ok := log.ExtractTrace(ctx)
+	if !ok || len(info.TraceID) != 32 || len(info.SpanID) != 16 || !info.Sampled {
+		t.Errorf("ExtractTrace() = %+v, %v", info, ok)
+	}
+	sp.Finish()
+
+	if err := closer(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&exported) == 0 {
+		t.Error("no span exported")
+	}
+}
+
+func TestNewOTelTracerProtocol(t *testing.T) {
+	if _, _, err := NewOTelTracerE("era", nil, OTelConfig{Protocol: "thrift"}); !errors.Is(err, ErrInit) {
+		t.Errorf("NewOTelTracerE() error = %v, want %v", err, ErrInit)
+	}
+}
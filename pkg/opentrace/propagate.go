@@ -0,0 +1,147 @@
+package opentrace
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/GaVender/era/pkg/log"
+)
+
+type (
+	// Header is a message header as kafka and most queue clients carry them.
+	Header struct {
+		Key   string
+		Value []byte
+	}
+
+	// HeadersCarrier lets the tracer read and write byte-slice headers.
+	HeadersCarrier []Header
+)
+
+// InjectMap writes the span in ctx into m, nothing is written when ctx carries no span.
+func InjectMap(ctx context.Context, tracer opentracing.Tracer, m map[string]string) error {
+	sp := opentracing.SpanFromContext(ctx)
+	if sp == nil {
+		return nil
+	}
+
+	return orGlobal(tracer).Inject(sp.Context(), opentracing.TextMap, opentracing.TextMapCarrier(m))
+}
+
+// ExtractMap reads the span context written by InjectMap, it is nil when m carries none.
+func ExtractMap(tracer opentracing.Tracer, m map[string]string) (opentracing.SpanContext, error) {
+	return extract(tracer, opentracing.TextMap, opentracing.TextMapCarrier(m))
+}
+
+// InjectHeaders appends the span in ctx to headers.
+func InjectHeaders(ctx context.Context, tracer opentracing.Tracer, headers []Header) ([]Header, error) {
+	sp := opentracing.SpanFromContext(ctx)
+	if sp == nil {
+		return headers, nil
+	}
+
+	carrier := HeadersCarrier(headers)
+	err := orGlobal(tracer).Inject(sp.Context(), opentracing.TextMap, &carrier)
+	return carrier, err
+}
+
+// ExtractHeaders reads the span context written by InjectHeaders, it is nil when headers carry none.
+func ExtractHeaders(tracer opentracing.Tracer, headers []Header) (opentracing.SpanContext, error) {
+	carrier := HeadersCarrier(headers)
+	return extract(tracer, opentracing.TextMap, &carrier)
+}
+
+// ExtractRequest reads the span context of the caller of r, it is nil when r carries none.
+func ExtractRequest(tracer opentracing.Tracer, r *http.Request) (opentracing.SpanContext, error) {
+	return extract(tracer, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+}
+
+// StartFrom starts op as a child of the remote span context, or as a root span when it is nil,
+// and returns ctx carrying the new span.
+func StartFrom(ctx context.Context, tracer opentracing.Tracer, op string, remote opentracing.SpanContext, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if remote != nil {
+		opts = append([]opentracing.StartSpanOption{opentracing.ChildOf(remote)}, opts...)
+	}
+
+	sp := orGlobal(tracer).StartSpan(op, opts...)
+	return sp, opentracing.ContextWithSpan(ctx, sp)
+}
+
+// Go runs fn in a goroutine under a span following from the span in ctx.
+// fn gets a context detached from the cancellation of ctx, which only keeps the span and the logger.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	tracer := opentracing.GlobalTracer()
+	var opts []opentracing.StartSpanOption
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		tracer = parent.Tracer()
+		opts = append(opts, opentracing.FollowsFrom(parent.Context()))
+	}
+
+	detached := context.Background()
+	if logger := log.FromContextOr(ctx, nil); logger != nil {
+		detached = log.IntoContext(detached, logger)
+	}
+
+	sp := tracer.StartSpan(funcName(fn), opts...)
+	detached = opentracing.ContextWithSpan(detached, sp)
+
+	go func() {
+		defer sp.Finish()
+		fn(detached)
+	}()
+}
+
+func (c HeadersCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, h := range c {
+		if err := handler(h.Key, string(h.Value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *HeadersCarrier) Set(key, val string) {
+	for i, h := range *c {
+		if h.Key == key {
+			(*c)[i].Value = []byte(val)
+			return
+		}
+	}
+
+	*c = append(*c, Header{Key: key, Value: []byte(val)})
+}
+
+func extract(tracer opentracing.Tracer, format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	sc, err := orGlobal(tracer).Extract(format, carrier)
+	if err == opentracing.ErrSpanContextNotFound {
+		return nil, nil
+	}
+
+	return sc, err
+}
+
+func orGlobal(tracer opentracing.Tracer) opentracing.Tracer {
+	if tracer == nil {
+		return opentracing.GlobalTracer()
+	}
+
+	return tracer
+}
+
+func funcName(fn interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return "go: " + name
+}
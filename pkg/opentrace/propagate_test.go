@@ -0,0 +1,105 @@
+package opentrace
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/GaVender/era/pkg/log"
+)
+
+func TestPropagate(t *testing.T) {
+	tests := []struct {
+		name   string
+		inject func(ctx context.Context, tracer opentracing.Tracer) (opentracing.SpanContext, error)
+	}{
+		{name: "map", inject: func(ctx context.Context, tracer opentracing.Tracer) (opentracing.SpanContext, error) {
+			m := map[string]string{}
+			if err := InjectMap(ctx, tracer, m); err != nil {
+				return nil, err
+			}
+			return ExtractMap(tracer, m)
+		}},
+		{name: "headers", inject: func(ctx context.Context, tracer opentracing.Tracer) (opentracing.SpanContext, error) {
+			headers, err := InjectHeaders(ctx, tracer, []Header{{Key: "topic", Value: []byte("order")}})
+			if err != nil {
+				return nil, err
+			}
+			return ExtractHeaders(tracer, headers)
+		}},
+		{name: "request", inject: func(ctx context.Context, tracer opentracing.Tracer) (opentracing.SpanContext, error) {
+			r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/order", nil)
+			sp := opentracing.SpanFromContext(ctx)
+			if err := tracer.Inject(sp.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)); err != nil {
+				return nil, err
+			}
+			return ExtractRequest(tracer, r)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			parent := tracer.StartSpan("producer")
+			ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+			remote, err := tt.inject(ctx, tracer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sp, _ := StartFrom(context.Background(), tracer, "consumer", remote)
+			sp.Finish()
+
+			got := tracer.FinishedSpans()[0]
+			want := parent.Context().(mocktracer.MockSpanContext)
+			if got.SpanContext.TraceID != want.TraceID || got.ParentID != want.SpanID {
+				t.Errorf("consumer span is not a child of producer: %+v", got)
+			}
+		})
+	}
+
+	if sc, err := ExtractMap(mocktracer.New(), map[string]string{}); sc != nil || err != nil {
+		t.Errorf("empty carrier = %v, %v", sc, err)
+	}
+}
+
+func TestGo(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("request")
+	logger := log.NewRecorder()
+
+	ctx, cancel := context.WithCancel(opentracing.ContextWithSpan(log.IntoContext(context.Background(), logger), parent))
+	canceled := make(chan struct{})
+	done := make(chan context.Context)
+	Go(ctx, func(ctx context.Context) {
+		<-canceled
+		log.FromContext(ctx).Infof("detached")
+		done <- ctx
+	})
+	cancel()
+	close(canceled)
+
+	var detached context.Context
+	select {
+	case detached = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("fn didn't run")
+	}
+
+	if detached.Err() != nil {
+		t.Errorf("detached ctx is canceled: %v", detached.Err())
+	}
+	if !logger.Contains(log.LevelInfo, "detached") {
+		t.Error("logger is not carried")
+	}
+
+	sp := opentracing.SpanFromContext(detached).(*mocktracer.MockSpan)
+	if sp.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Errorf("parent = %d", sp.ParentID)
+	}
+}
@@ -0,0 +1,171 @@
+package opentrace
+
+import (
+	"errors"
+	"time"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/thrift-gen/sampling"
+
+	"github.com/GaVender/era/config"
+)
+
+type (
+	// SamplingConfig decides at span start with Type and Rate, Operations overrides the probability per operation.
+	// Traces not sampled at start are still kept when a span fails with SampleErrors,
+	// or runs longer than SlowThreshold, this decision is deferred until the span is tagged or finished,
+	// so spans finished before it are not reported. Injecting the span into a request makes the decision final.
+	SamplingConfig struct {
+		Type          string
+		Rate          float64
+		Operations    map[string]float64
+		SampleErrors  bool
+		SlowThreshold time.Duration
+	}
+
+	sampler struct {
+		jaeger.SamplerV2Base
+		head          jaeger.SamplerV2
+		sampleErrors  bool
+		slowThreshold time.Duration
+	}
+
+	v1Sampler struct {
+		jaeger.SamplerV2Base
+		sampler jaeger.Sampler
+	}
+)
+
+const (
+	SamplerConst         = "const"
+	SamplerProbabilistic = "probabilistic"
+	SamplerRateLimiting  = "ratelimiting"
+
+	samplerTypeError = "error"
+	samplerTypeSlow  = "slow"
+)
+
+var (
+	ErrSampler = errors.New("invalid sampling config")
+)
+
+// DefaultSampling keeps everything outside pre and prd, where it samples a share of the traces
+// but always keeps the failed and slow ones.
+func DefaultSampling(env string) SamplingConfig {
+	switch env {
+	case config.Prd:
+		return SamplingConfig{Type: SamplerRateLimiting, Rate: 10, SampleErrors: true, SlowThreshold: time.Second}
+	case config.Pre:
+		return SamplingConfig{Type: SamplerProbabilistic, Rate: 0.1, SampleErrors: true, SlowThreshold: 500 * time.Millisecond}
+	default:
+		return SamplingConfig{Type: SamplerConst, Rate: 1}
+	}
+}
+
+// EnvSampling is DefaultSampling of config.Env().
+func EnvSampling() SamplingConfig {
+	return DefaultSampling(config.Env())
+}
+
+func NewSampler(cfg SamplingConfig) (jaeger.Sampler, error) {
+	var head jaeger.SamplerV2
+
+	switch {
+	case len(cfg.Operations) > 0:
+		strategies := &sampling.PerOperationSamplingStrategies{DefaultSamplingProbability: cfg.Rate}
+		for op, rate := range cfg.Operations {
+			strategies.PerOperationStrategies = append(strategies.PerOperationStrategies, &sampling.OperationSamplingStrategy{
+				Operation:             op,
+				ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{SamplingRate: rate},
+			})
+		}
+		head = jaeger.NewPerOperationSampler(jaeger.PerOperationSamplerParams{Strategies: strategies})
+	case cfg.Type == SamplerConst || len(cfg.Type) == 0:
+		head = &v1Sampler{sampler: jaeger.NewConstSampler(cfg.Rate != 0)}
+	case cfg.Type == SamplerProbabilistic:
+		s, err := jaeger.NewProbabilisticSampler(cfg.Rate)
+		if err != nil {
+			return nil, ErrSampler
+		}
+		head = &v1Sampler{sampler: s}
+	case cfg.Type == SamplerRateLimiting:
+		head = &v1Sampler{sampler: jaeger.NewRateLimitingSampler(cfg.Rate)}
+	default:
+		return nil, ErrSampler
+	}
+
+	if !cfg.SampleErrors && cfg.SlowThreshold <= 0 {
+		return head.(jaeger.Sampler), nil
+	}
+
+	return &sampler{head: head, sampleErrors: cfg.SampleErrors, slowThreshold: cfg.SlowThreshold}, nil
+}
+
+func (s *sampler) OnCreateSpan(span *jaeger.Span) jaeger.SamplingDecision {
+	return s.deferred(s.head.OnCreateSpan(span))
+}
+
+func (s *sampler) OnSetOperationName(span *jaeger.Span, operationName string) jaeger.SamplingDecision {
+	return s.deferred(s.head.OnSetOperationName(span, operationName))
+}
+
+func (s *sampler) OnSetTag(span *jaeger.Span, key string, value interface{}) jaeger.SamplingDecision {
+	if s.sampleErrors && key == string(ext.Error) && value == true {
+		return jaeger.SamplingDecision{Sample: true, Tags: samplerTags(samplerTypeError)}
+	}
+
+	return jaeger.SamplingDecision{Retryable: true}
+}
+
+func (s *sampler) OnFinishSpan(span *jaeger.Span) jaeger.SamplingDecision {
+	if s.slowThreshold > 0 && span.Duration() >= s.slowThreshold {
+		return jaeger.SamplingDecision{Sample: true, Tags: samplerTags(samplerTypeSlow)}
+	}
+
+	// the trace stays open until its root finishes
+	return jaeger.SamplingDecision{Retryable: span.SpanContext().ParentID() != 0}
+}
+
+func (s *sampler) Close() {
+	s.head.Close()
+}
+
+// deferred keeps an unsampled decision open so errors and slow spans can still be sampled.
+func (s *sampler) deferred(d jaeger.SamplingDecision) jaeger.SamplingDecision {
+	if !d.Sample {
+		d.Retryable = true
+	}
+
+	return d
+}
+
+// OnCreateSpan only samples root spans, the children share the decision of their root.
+func (s *v1Sampler) OnCreateSpan(span *jaeger.Span) jaeger.SamplingDecision {
+	if span.SpanContext().ParentID() != 0 {
+		return jaeger.SamplingDecision{Retryable: true}
+	}
+
+	sampled, tags := s.sampler.IsSampled(span.SpanContext().TraceID(), span.OperationName())
+	return jaeger.SamplingDecision{Sample: sampled, Tags: tags}
+}
+
+func (s *v1Sampler) OnSetOperationName(span *jaeger.Span, operationName string) jaeger.SamplingDecision {
+	return jaeger.SamplingDecision{Retryable: true}
+}
+
+func (s *v1Sampler) OnSetTag(span *jaeger.Span, key string, value interface{}) jaeger.SamplingDecision {
+	return jaeger.SamplingDecision{Retryable: true}
+}
+
+func (s *v1Sampler) OnFinishSpan(span *jaeger.Span) jaeger.SamplingDecision {
+	return jaeger.SamplingDecision{Retryable: true}
+}
+
+func (s *v1Sampler) Close() {
+	s.sampler.Close()
+}
+
+func samplerTags(typ string) []jaeger.Tag {
+	return []jaeger.Tag{jaeger.NewTag(jaeger.SamplerTypeTagKey, typ)}
+}
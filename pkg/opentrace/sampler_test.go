@@ -0,0 +1,65 @@
+package opentrace
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestSampler(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      SamplingConfig
+		err      error
+		duration time.Duration
+		want     bool
+	}{
+		{name: "const", cfg: SamplingConfig{Type: SamplerConst, Rate: 1}, want: true},
+		{name: "dropped", cfg: SamplingConfig{Type: SamplerProbabilistic, SampleErrors: true, SlowThreshold: time.Second}},
+		{name: "error", cfg: SamplingConfig{Type: SamplerProbabilistic, SampleErrors: true}, err: errors.New("timeout"), want: true},
+		{name: "error ignored", cfg: SamplingConfig{Type: SamplerProbabilistic}, err: errors.New("timeout")},
+		{name: "slow", cfg: SamplingConfig{Type: SamplerProbabilistic, SlowThreshold: time.Second}, duration: 2 * time.Second, want: true},
+		{name: "operation", cfg: SamplingConfig{Operations: map[string]float64{"request": 1}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler, err := NewSampler(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reporter := jaeger.NewInMemoryReporter()
+			tracer, closer := jaeger.NewTracer("era", sampler, reporter)
+			defer closer.Close()
+
+			root := tracer.StartSpan("request", opentracing.StartTime(time.Now().Add(-tt.duration)))
+			child := tracer.StartSpan("redis: get", opentracing.ChildOf(root.Context()))
+			Finish(child, tt.err)
+			root.Finish()
+
+			if got := root.Context().(jaeger.SpanContext).IsSampled(); got != tt.want {
+				t.Errorf("sampled = %v, want %v", got, tt.want)
+			}
+			if tt.want && reporter.SpansSubmitted() == 0 {
+				t.Error("no span reported")
+			}
+		})
+	}
+
+	if _, err := NewSampler(SamplingConfig{Type: "remote"}); !errors.Is(err, ErrSampler) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestDefaultSampling(t *testing.T) {
+	if cfg := DefaultSampling("dev"); cfg.Type != SamplerConst || cfg.Rate != 1 {
+		t.Errorf("dev = %+v", cfg)
+	}
+	if cfg := DefaultSampling("prd"); !cfg.SampleErrors || cfg.SlowThreshold <= 0 {
+		t.Errorf("prd = %+v", cfg)
+	}
+}
@@ -0,0 +1,72 @@
+package opentrace
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// StartChild starts op as a child of the span in ctx, or as a root span when there is none,
+// and returns ctx carrying the new span. A nil tracer falls back to opentracing.GlobalTracer().
+func StartChild(ctx context.Context, tracer opentracing.Tracer, op string, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append([]opentracing.StartSpanOption{opentracing.ChildOf(parent.Context())}, opts...)
+	}
+
+	sp := orGlobal(tracer).StartSpan(op, opts...)
+	return sp, opentracing.ContextWithSpan(ctx, sp)
+}
+
+// Finish records err onto sp, if any, and finishes it.
+func Finish(sp opentracing.Span, err error) {
+	SetError(sp, err)
+	sp.Finish()
+}
+
+// DBTags are the client span tags of a database call, in both the opentracing and the OpenTelemetry conventions.
+func DBTags(system, instance, addr string) opentracing.Tags {
+	tags := opentracing.Tags{
+		string(ext.SpanKind):        ext.SpanKindRPCClientEnum,
+		string(ext.DBType):          system,
+		string(semconv.DBSystemKey): system,
+	}
+
+	if len(instance) > 0 {
+		tags[string(ext.DBInstance)] = instance
+		tags[string(semconv.DBNameKey)] = instance
+	}
+
+	return peerTags(tags, addr)
+}
+
+// HTTPClientTags are the client span tags of an outgoing request.
+func HTTPClientTags(method, url, addr string) opentracing.Tags {
+	tags := opentracing.Tags{
+		string(ext.SpanKind):                 ext.SpanKindRPCClientEnum,
+		string(ext.HTTPMethod):               method,
+		string(ext.HTTPUrl):                  url,
+		string(semconv.HTTPRequestMethodKey): method,
+		string(semconv.URLFullKey):           url,
+	}
+
+	return peerTags(tags, addr)
+}
+
+func peerTags(tags opentracing.Tags, addr string) opentracing.Tags {
+	if len(addr) == 0 {
+		return tags
+	}
+
+	tags[string(ext.PeerAddress)] = addr
+	for _, kv := range ServerAttributes(addr) {
+		tags[string(kv.Key)] = kv.Value.AsInterface()
+	}
+
+	return tags
+}
@@ -0,0 +1,66 @@
+package opentrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestStartChild(t *testing.T) {
+	tests := []struct {
+		name       string
+		parent     bool
+		err        error
+		wantParent bool
+	}{
+		{name: "root"},
+		{name: "child", parent: true, wantParent: true},
+		{name: "failed", err: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			ctx := context.Background()
+
+			var parent opentracing.Span
+			if tt.parent {
+				parent = tracer.StartSpan("parent")
+				ctx = opentracing.ContextWithSpan(ctx, parent)
+			}
+
+			sp, ctx := StartChild(ctx, tracer, "redis: get", DBTags("redis", "0", "127.0.0.1:6379"))
+			if opentracing.SpanFromContext(ctx) != sp {
+				t.Error("ctx doesn't carry the child span")
+			}
+			Finish(sp, tt.err)
+
+			finished := tracer.FinishedSpans()
+			if len(finished) != 1 {
+				t.Fatalf("finished %d spans", len(finished))
+			}
+
+			got := finished[0]
+			if tt.wantParent && got.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+				t.Errorf("parent = %d", got.ParentID)
+			}
+			if !tt.wantParent && got.ParentID != 0 {
+				t.Errorf("root span has parent %d", got.ParentID)
+			}
+
+			tags := got.Tags()
+			if tags["db.type"] != "redis" || tags["peer.address"] != "127.0.0.1:6379" || tags["server.port"] != int64(6379) {
+				t.Errorf("tags = %v", tags)
+			}
+			if failed, _ := tags["error"].(bool); failed != (tt.err != nil) {
+				t.Errorf("error tag = %v, want %v", tags["error"], tt.err != nil)
+			}
+			if tt.err != nil && len(got.Logs()) == 0 {
+				t.Error("error not logged onto the span")
+			}
+		})
+	}
+}
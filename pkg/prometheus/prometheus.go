@@ -17,7 +17,8 @@ type (
 	}
 
 	Server struct {
-		logger log.Logger
+		logger   log.Logger
+		handlers map[string]http.Handler
 	}
 
 	Option func(*Server)
@@ -32,7 +33,7 @@ var (
 )
 
 func NewService(cfg Config, opts ...Option) (*Server, error) {
-	s := &Server{}
+	s := &Server{handlers: make(map[string]http.Handler)}
 
 	if !cfg.Disabled {
 		if len(cfg.Host) == 0 {
@@ -52,9 +53,15 @@ func NewService(cfg Config, opts ...Option) (*Server, error) {
 			return nil, err
 		}
 
+		mux := http.NewServeMux()
+		for pattern, h := range s.handlers {
+			mux.Handle(pattern, h)
+		}
+		mux.Handle("/", http.DefaultServeMux)
+
 		go func() {
 			http.Handle("/metrics", promhttp.Handler())
-			panic("prometheus init: " + http.ListenAndServe(cfg.Host, nil).Error())
+			panic("prometheus init: " + http.ListenAndServe(cfg.Host, mux).Error())
 		}()
 	}
 
@@ -66,3 +73,11 @@ func WithLogger(logger log.Logger) Option {
 		server.logger = logger
 	}
 }
+
+// WithHandler mounts h next to /metrics, e.g. WithHandler("/log/level", logger.Levels()),
+// on the metrics server only: it is not added to http.DefaultServeMux.
+func WithHandler(pattern string, h http.Handler) Option {
+	return func(server *Server) {
+		server.handlers[pattern] = h
+	}
+}
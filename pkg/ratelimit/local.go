@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// TokenBucket refills rate permits per second per key, up to burst of them.
+	TokenBucket struct {
+		name    string
+		rate    float64
+		burst   float64
+		now     func() time.Time
+		mu      sync.Mutex
+		buckets map[string]*bucket
+	}
+
+	// SlidingWindow hands out limit permits per key over any window, it weighs the count
+	// of the previous fixed window by how much of it still overlaps the sliding one.
+	SlidingWindow struct {
+		name    string
+		limit   int
+		window  time.Duration
+		now     func() time.Time
+		mu      sync.Mutex
+		windows map[string]*slot
+	}
+
+	bucket struct {
+		tokens float64
+		at     time.Time
+	}
+
+	slot struct {
+		index int64
+		prev  int
+		cur   int
+	}
+)
+
+func NewTokenBucket(name string, rate float64, burst int, opts ...Option) *TokenBucket {
+	if rate <= 0 {
+		panic("ratelimit: rate must be positive")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &TokenBucket{
+		name:    name,
+		rate:    rate,
+		burst:   float64(burst),
+		now:     newOptions(opts).now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *TokenBucket) Name() string {
+	return l.name
+}
+
+func (l *TokenBucket) Take(_ context.Context, key string) (time.Duration, error) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, at: now}
+		l.buckets[key] = b
+	}
+	if now.After(b.at) {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.at).Seconds()*l.rate)
+		b.at = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, nil
+	}
+
+	return time.Duration(math.Max(1, math.Round((1-b.tokens)*float64(time.Second)/l.rate))), nil
+}
+
+func NewSlidingWindow(name string, limit int, window time.Duration, opts ...Option) *SlidingWindow {
+	if limit < 1 || window <= 0 {
+		panic("ratelimit: limit and window must be positive")
+	}
+
+	return &SlidingWindow{
+		name:    name,
+		limit:   limit,
+		window:  window,
+		now:     newOptions(opts).now,
+		windows: make(map[string]*slot),
+	}
+}
+
+func (l *SlidingWindow) Name() string {
+	return l.name
+}
+
+func (l *SlidingWindow) Take(_ context.Context, key string) (time.Duration, error) {
+	now := l.now().UnixNano()
+	index := now / int64(l.window)
+	elapsed := time.Duration(now - index*int64(l.window))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	switch {
+	case !ok:
+		w = &slot{index: index}
+		l.windows[key] = w
+	case index == w.index+1:
+		w.index, w.prev, w.cur = index, w.cur, 0
+	case index > w.index+1:
+		w.index, w.prev, w.cur = index, 0, 0
+	}
+
+	wait := slidingWait(w.prev, w.cur, l.limit, elapsed, l.window)
+	if wait == 0 {
+		w.cur++
+	}
+
+	return wait, nil
+}
+
+// slidingWait is how long until one more permit fits in the sliding window, 0 when it does now.
+func slidingWait(prev, cur, limit int, elapsed, window time.Duration) time.Duration {
+	overlap := float64(window-elapsed) / float64(window)
+	if float64(prev)*overlap+float64(cur)+1 <= float64(limit) {
+		return 0
+	}
+
+	if prev == 0 || cur+1 > limit {
+		return window - elapsed
+	}
+
+	wait := float64(window) - float64(limit-cur-1)*float64(window)/float64(prev) - float64(elapsed)
+	return time.Duration(math.Max(1, math.Round(wait)))
+}
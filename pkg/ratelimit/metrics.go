@@ -0,0 +1,31 @@
+package ratelimit
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	namespace = "era"
+	subsystem = "ratelimit"
+
+	metricsRateLimitWaitHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "wait_seconds",
+		Help:      "time waited for a permit",
+		Buckets:   []float64{0, .001, .005, .01, .05, .1, .5, 1, 5},
+	}, []string{
+		"name",
+	})
+
+	metricsRateLimitRejectedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "rejected_total",
+		Help:      "total number of permits not granted",
+	}, []string{
+		"name",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(metricsRateLimitWaitHistogram, metricsRateLimitRejectedCounter)
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// Limiter hands out permits per key, Take takes one when it is free and otherwise returns
+	// how long until the next one is, without taking it.
+	Limiter interface {
+		Name() string
+		Take(ctx context.Context, key string) (wait time.Duration, err error)
+	}
+
+	// Mode is how Acquire behaves when no permit is free.
+	Mode int
+
+	// Error is returned when no permit could be taken, errors.Is matches it against ErrLimited.
+	Error struct {
+		Name string
+		Key  string
+		Wait time.Duration
+	}
+
+	options struct {
+		now      func() time.Time
+		fallback Limiter
+	}
+
+	Option func(*options)
+)
+
+const (
+	// ModeWait blocks until a permit is free or ctx is done.
+	ModeWait Mode = iota
+	// ModeFailFast returns an *Error right away.
+	ModeFailFast
+)
+
+var (
+	ErrLimited = errors.New("rate limited")
+)
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithFallback takes the permits from l while redis is unreachable instead of failing.
+func WithFallback(l Limiter) Option {
+	return func(o *options) {
+		o.fallback = l
+	}
+}
+
+// Acquire takes a permit of key from l, waiting for it or failing fast depending on mode.
+func Acquire(ctx context.Context, l Limiter, key string, mode Mode) error {
+	if mode == ModeFailFast {
+		return Allow(ctx, l, key)
+	}
+
+	return Wait(ctx, l, key)
+}
+
+// Allow takes a permit of key from l if one is free.
+func Allow(ctx context.Context, l Limiter, key string) error {
+	wait, err := l.Take(ctx, key)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		metricsRateLimitRejectedCounter.WithLabelValues(l.Name()).Inc()
+		return &Error{Name: l.Name(), Key: key, Wait: wait}
+	}
+
+	metricsRateLimitWaitHistogram.WithLabelValues(l.Name()).Observe(0)
+	return nil
+}
+
+// Wait blocks until l hands out a permit of key, it gives up right away
+// when the permit would only be free after the deadline of ctx.
+func Wait(ctx context.Context, l Limiter, key string) error {
+	beginTime := time.Now()
+	for {
+		wait, err := l.Take(ctx, key)
+		if err != nil {
+			return err
+		}
+		if wait <= 0 {
+			metricsRateLimitWaitHistogram.WithLabelValues(l.Name()).Observe(time.Since(beginTime).Seconds())
+			return nil
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			metricsRateLimitRejectedCounter.WithLabelValues(l.Name()).Inc()
+			return &Error{Name: l.Name(), Key: key, Wait: wait}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			metricsRateLimitRejectedCounter.WithLabelValues(l.Name()).Inc()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s %s: next permit in %s", ErrLimited.Error(), e.Name, e.Key, e.Wait)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrLimited
+}
+
+func newOptions(opts []Option) options {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
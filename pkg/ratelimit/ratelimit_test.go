@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestLimiters(t *testing.T) {
+	type take struct {
+		after    time.Duration
+		wantWait time.Duration
+	}
+
+	tests := []struct {
+		name    string
+		limiter func(now func() time.Time) Limiter
+		takes   []take
+	}{
+		{
+			name: "token bucket burst",
+			limiter: func(now func() time.Time) Limiter {
+				return NewTokenBucket("bucket", 10, 2, WithClock(now))
+			},
+			takes: []take{{}, {}, {wantWait: 100 * time.Millisecond}, {after: 50 * time.Millisecond, wantWait: 50 * time.Millisecond}},
+		},
+		{
+			name: "token bucket refill",
+			limiter: func(now func() time.Time) Limiter {
+				return NewTokenBucket("bucket", 10, 1, WithClock(now))
+			},
+			takes: []take{{}, {after: 100 * time.Millisecond}, {after: 150 * time.Millisecond}, {wantWait: 100 * time.Millisecond}},
+		},
+		{
+			name: "sliding window full",
+			limiter: func(now func() time.Time) Limiter {
+				return NewSlidingWindow("window", 2, time.Second, WithClock(now))
+			},
+			takes: []take{{}, {}, {after: 500 * time.Millisecond, wantWait: 500 * time.Millisecond}},
+		},
+		{
+			name: "sliding window overlap",
+			limiter: func(now func() time.Time) Limiter {
+				return NewSlidingWindow("window", 2, time.Second, WithClock(now))
+			},
+			takes: []take{{}, {}, {after: 1250 * time.Millisecond, wantWait: 250 * time.Millisecond}, {after: 250 * time.Millisecond}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &clock{now: time.Unix(1585000000, 0)}
+			l := tt.limiter(c.Now)
+
+			for i, tk := range tt.takes {
+				c.now = c.now.Add(tk.after)
+				wait, err := l.Take(context.Background(), "partner")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if wait != tk.wantWait {
+					t.Errorf("take %d: wait = %s, want %s", i, wait, tk.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	l := NewTokenBucket("acquire", 50, 1)
+	if err := Acquire(context.Background(), l, "partner", ModeFailFast); err != nil {
+		t.Fatalf("first permit: %v", err)
+	}
+
+	var e *Error
+	if err := Acquire(context.Background(), l, "partner", ModeFailFast); !errors.As(err, &e) || !errors.Is(err, ErrLimited) || e.Key != "partner" {
+		t.Errorf("fail fast = %v", err)
+	}
+
+	if err := Acquire(context.Background(), l, "other", ModeFailFast); err != nil {
+		t.Errorf("other key: %v", err)
+	}
+
+	if err := Acquire(context.Background(), l, "partner", ModeWait); err != nil {
+		t.Errorf("wait = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := Acquire(ctx, l, "partner", ModeWait); !errors.Is(err, ErrLimited) {
+		t.Errorf("wait past deadline = %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis/v7"
+
+	"github.com/GaVender/era/pkg/redis"
+)
+
+type (
+	// RedisLimiter shares its permits between every instance using the same name and redis,
+	// the clocks of the instances are assumed to be in sync.
+	RedisLimiter struct {
+		name     string
+		client   redis.Redis
+		script   *goredis.Script
+		args     func(key string, now time.Time) ([]string, []interface{})
+		now      func() time.Time
+		fallback Limiter
+	}
+)
+
+const (
+	keyPrefix = "era:ratelimit:"
+)
+
+var (
+	// tokenBucketScript returns the wait in microseconds, ARGV is rate per second, burst and now in microseconds.
+	tokenBucketScript = goredis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(state[1]) or burst
+local at = tonumber(state[2]) or now
+if now > at then
+	tokens = math.min(burst, tokens + (now - at) * rate / 1e6)
+	at = now
+end
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) * 1e6 / rate)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'at', tostring(at))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return wait
+`)
+
+	// slidingWindowScript returns the wait in microseconds, KEYS are the current and previous window,
+	// ARGV is limit, window and the time elapsed in the current one in microseconds.
+	slidingWindowScript = goredis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local cur = tonumber(redis.call('GET', KEYS[1])) or 0
+local prev = tonumber(redis.call('GET', KEYS[2])) or 0
+if prev * (window - elapsed) / window + cur + 1 <= limit then
+	redis.call('INCR', KEYS[1])
+	redis.call('PEXPIRE', KEYS[1], math.ceil(window * 2 / 1000))
+	return 0
+end
+if prev == 0 or cur + 1 > limit then
+	return window - elapsed
+end
+return math.max(1, math.ceil(window - (limit - cur - 1) * window / prev - elapsed))
+`)
+)
+
+// NewRedisTokenBucket is a TokenBucket shared through client.
+func NewRedisTokenBucket(name string, client redis.Redis, rate float64, burst int, opts ...Option) *RedisLimiter {
+	if rate <= 0 {
+		panic("ratelimit: rate must be positive")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return newRedisLimiter(name, client, tokenBucketScript, func(key string, now time.Time) ([]string, []interface{}) {
+		return []string{redisKey(name, key)}, []interface{}{rate, burst, now.UnixNano() / int64(time.Microsecond)}
+	}, opts)
+}
+
+// NewRedisSlidingWindow is a SlidingWindow shared through client.
+func NewRedisSlidingWindow(name string, client redis.Redis, limit int, window time.Duration, opts ...Option) *RedisLimiter {
+	if limit < 1 || window < time.Microsecond {
+		panic("ratelimit: limit and window must be positive")
+	}
+
+	micros := int64(window / time.Microsecond)
+	return newRedisLimiter(name, client, slidingWindowScript, func(key string, now time.Time) ([]string, []interface{}) {
+		t := now.UnixNano() / int64(time.Microsecond)
+		index := t / micros
+		k := redisKey(name, key)
+		return []string{k + strconv.FormatInt(index, 10), k + strconv.FormatInt(index-1, 10)},
+			[]interface{}{limit, micros, t - index*micros}
+	}, opts)
+}
+
+func newRedisLimiter(name string, client redis.Redis, script *goredis.Script,
+	args func(string, time.Time) ([]string, []interface{}), opts []Option) *RedisLimiter {
+	o := newOptions(opts)
+
+	return &RedisLimiter{
+		name:     name,
+		client:   client,
+		script:   script,
+		args:     args,
+		now:      o.now,
+		fallback: o.fallback,
+	}
+}
+
+func (l *RedisLimiter) Name() string {
+	return l.name
+}
+
+func (l *RedisLimiter) Take(ctx context.Context, key string) (time.Duration, error) {
+	keys, args := l.args(key, l.now())
+
+	wait, err := l.script.Run(l.client.WithContext(ctx), keys, args...).Int64()
+	if err != nil {
+		if l.fallback != nil {
+			return l.fallback.Take(ctx, key)
+		}
+		return 0, err
+	}
+
+	return time.Duration(wait) * time.Microsecond, nil
+}
+
+// redisKey keeps the windows of a key in the same cluster slot.
+func redisKey(name, key string) string {
+	return keyPrefix + "{" + name + ":" + key + "}:"
+}
@@ -0,0 +1,392 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type (
+	// Rules select what gets masked:
+	// Headers and Queries are names, matched case-insensitively,
+	// JSONPaths are dotted paths where * matches one level and ** any number of levels, e.g. "**.password",
+	// SQLColumns are the columns whose bound values are masked,
+	// RedisKeys are path.Match patterns, e.g. "session:*", whose values are masked,
+	// Patterns are regular expressions masked in every string, e.g. PatternCardNumber.
+	Rules struct {
+		Headers    []string
+		Queries    []string
+		JSONPaths  []string
+		SQLColumns []string
+		RedisKeys  []string
+		Patterns   []string
+		Mask       string
+	}
+
+	Redactor struct {
+		mask       string
+		headers    map[string]bool
+		queries    map[string]bool
+		jsonPaths  [][]string
+		sqlColumns map[string]bool
+		redisKeys  []string
+		patterns   []*regexp.Regexp
+	}
+)
+
+const (
+	DefaultMask = "******"
+
+	PatternCardNumber = `\b\d{4}[ -]?\d{4}[ -]?\d{4}[ -]?\d{1,7}\b`
+	PatternPhone      = `\b1[3-9]\d{9}\b`
+	PatternEmail      = `[\w.+-]+@[\w-]+\.[\w.-]+`
+)
+
+var (
+	defaultMu       sync.RWMutex
+	defaultRedactor = MustNew(DefaultRules())
+
+	insertRegexp   = regexp.MustCompile(`(?is)^\s*(?:insert|replace)\s+(?:into\s+)?\S+\s*\(([^)]*)\)\s*values?\s*`)
+	columnRegexp   = regexp.MustCompile("(?i)([\\w`.]+)\\s*(?:=|<>|!=|<=|>=|<|>|\\s+like|\\s+in\\s*\\((?:\\s*\\?\\s*,)*)\\s*$")
+	sensitiveWords = []string{"password", "passwd", "secret", "token"}
+)
+
+// DefaultRules masks credentials in headers, query strings, JSON bodies, SQL and redis auth.
+func DefaultRules() Rules {
+	var paths, columns []string
+	for _, w := range sensitiveWords {
+		paths = append(paths, "**."+w)
+		columns = append(columns, w)
+	}
+
+	return Rules{
+		Headers:    []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"},
+		Queries:    append([]string{"access_token", "api_key"}, sensitiveWords...),
+		JSONPaths:  paths,
+		SQLColumns: columns,
+		Patterns:   []string{PatternCardNumber},
+	}
+}
+
+func New(rules Rules) (*Redactor, error) {
+	r := &Redactor{
+		mask:       rules.Mask,
+		headers:    make(map[string]bool, len(rules.Headers)),
+		queries:    make(map[string]bool, len(rules.Queries)),
+		sqlColumns: make(map[string]bool, len(rules.SQLColumns)),
+		redisKeys:  rules.RedisKeys,
+	}
+
+	if len(r.mask) == 0 {
+		r.mask = DefaultMask
+	}
+
+	for _, h := range rules.Headers {
+		r.headers[http.CanonicalHeaderKey(h)] = true
+	}
+
+	for _, q := range rules.Queries {
+		r.queries[strings.ToLower(q)] = true
+	}
+
+	for _, p := range rules.JSONPaths {
+		r.jsonPaths = append(r.jsonPaths, strings.Split(p, "."))
+	}
+
+	for _, c := range rules.SQLColumns {
+		r.sqlColumns[strings.ToLower(c)] = true
+	}
+
+	for _, k := range rules.RedisKeys {
+		if _, err := path.Match(k, ""); err != nil {
+			return nil, fmt.Errorf("redis key pattern %q: %w", k, err)
+		}
+	}
+
+	for _, p := range rules.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+func MustNew(rules Rules) *Redactor {
+	r, err := New(rules)
+	if err != nil {
+		panic("redact init: " + err.Error())
+	}
+
+	return r
+}
+
+// Default is used by the era clients without a redactor of their own.
+func Default() *Redactor {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultRedactor
+}
+
+func SetDefault(r *Redactor) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultRedactor = r
+}
+
+// Or returns r, or the default redactor when r is nil.
+func Or(r *Redactor) *Redactor {
+	if r != nil {
+		return r
+	}
+
+	return Default()
+}
+
+func (r *Redactor) Mask() string {
+	return r.mask
+}
+
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, r.mask)
+	}
+
+	return s
+}
+
+func (r *Redactor) Header(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if r.headers[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{r.mask}
+			continue
+		}
+
+		values := make([]string, len(v))
+		for i, s := range v {
+			values[i] = r.String(s)
+		}
+		out[k] = values
+	}
+
+	return out
+}
+
+func (r *Redactor) Query(q url.Values) url.Values {
+	out := make(url.Values, len(q))
+	for k, v := range q {
+		if r.queries[strings.ToLower(k)] {
+			out[k] = []string{r.mask}
+			continue
+		}
+
+		values := make([]string, len(v))
+		for i, s := range v {
+			values[i] = r.String(s)
+		}
+		out[k] = values
+	}
+
+	return out
+}
+
+// JSON masks the values under the JSON paths, a body which is not JSON only goes through the patterns.
+func (r *Redactor) JSON(body []byte) string {
+	if len(r.jsonPaths) == 0 || len(body) == 0 {
+		return r.String(string(body))
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return r.String(string(body))
+	}
+
+	for _, p := range r.jsonPaths {
+		v = r.maskPath(v, p)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return r.String(string(body))
+	}
+
+	return r.String(string(b))
+}
+
+// SQLVars masks the values bound to the SQL columns, matched by the placeholder they fill.
+func (r *Redactor) SQLVars(sql string, vars []interface{}) []interface{} {
+	out := make([]interface{}, len(vars))
+	for i, v := range vars {
+		if s, ok := v.(string); ok {
+			out[i] = r.String(s)
+		} else {
+			out[i] = v
+		}
+	}
+
+	if len(r.sqlColumns) == 0 {
+		return out
+	}
+
+	for i, col := range placeholderColumns(sql) {
+		if i >= len(out) {
+			break
+		}
+		if r.sqlColumns[strings.ToLower(col)] {
+			out[i] = r.mask
+		}
+	}
+
+	return out
+}
+
+// RedisArgs masks the values of the keys matching the redis key patterns and the AUTH password.
+func (r *Redactor) RedisArgs(args []interface{}) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, a := range args {
+		if s, ok := a.(string); ok {
+			out[i] = r.String(s)
+		} else {
+			out[i] = a
+		}
+	}
+
+	if len(out) < 2 {
+		return out
+	}
+
+	switch strings.ToLower(fmt.Sprint(args[0])) {
+	case "auth":
+		for i := 1; i < len(out); i++ {
+			out[i] = r.mask
+		}
+	case "mset", "msetnx":
+		for i := 1; i+1 < len(out); i += 2 {
+			if r.RedisKey(fmt.Sprint(args[i])) {
+				out[i+1] = r.mask
+			}
+		}
+	default:
+		if r.RedisKey(fmt.Sprint(args[1])) {
+			for i := 2; i < len(out); i++ {
+				out[i] = r.mask
+			}
+		}
+	}
+
+	return out
+}
+
+// RedisKey reports whether the values of key are masked.
+func (r *Redactor) RedisKey(key string) bool {
+	for _, p := range r.redisKeys {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Redactor) maskPath(v interface{}, p []string) interface{} {
+	if len(p) == 0 {
+		return r.mask
+	}
+
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			switch {
+			case p[0] == "**":
+				node[k] = r.maskPath(child, p)
+				if len(p) > 1 && (p[1] == k || p[1] == "*") {
+					node[k] = r.maskPath(child, p[2:])
+				}
+			case p[0] == "*" || p[0] == k:
+				node[k] = r.maskPath(child, p[1:])
+			}
+		}
+	case []interface{}:
+		for i, child := range node {
+			if p[0] == "**" {
+				node[i] = r.maskPath(child, p)
+			} else if p[0] == "*" {
+				node[i] = r.maskPath(child, p[1:])
+			}
+		}
+	}
+
+	return v
+}
+
+// placeholderColumns returns the column each ? of sql is bound to, or "" when it can't tell.
+func placeholderColumns(sql string) []string {
+	var (
+		columns  []string
+		insert   []string
+		values   int
+		start    int
+		quote    byte
+		inValues bool
+	)
+
+	if m := insertRegexp.FindStringSubmatchIndex(sql); m != nil {
+		for _, c := range strings.Split(sql[m[2]:m[3]], ",") {
+			insert = append(insert, strings.Trim(strings.TrimSpace(c), "`"))
+		}
+		start, inValues = m[1], len(insert) > 0
+		for i := 0; i < start; i++ {
+			if sql[i] == '?' {
+				columns = append(columns, "")
+			}
+		}
+	}
+
+	for i := start; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			if inValues {
+				columns = append(columns, insert[values%len(insert)])
+				values++
+				continue
+			}
+
+			prefix := sql[:i]
+			if len(prefix) > 256 {
+				prefix = prefix[len(prefix)-256:]
+			}
+
+			col := ""
+			if m := columnRegexp.FindStringSubmatch(prefix); m != nil {
+				col = strings.Trim(m[1], "`")
+				if dot := strings.LastIndexByte(col, '.'); dot >= 0 {
+					col = strings.Trim(col[dot+1:], "`")
+				}
+			}
+			columns = append(columns, col)
+		case c == ')' && inValues && values%len(insert) == 0:
+			if rest := strings.TrimSpace(sql[i+1:]); len(rest) > 0 && rest[0] != ',' {
+				inValues = false
+			}
+		}
+	}
+
+	return columns
+}
@@ -0,0 +1,85 @@
+package redact
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSQLVars(t *testing.T) {
+	r := MustNew(Rules{SQLColumns: []string{"password", "phone"}})
+
+	tests := []struct {
+		name string
+		sql  string
+		vars []interface{}
+		want []interface{}
+	}{
+		{
+			name: "where",
+			sql:  "SELECT * FROM `users` WHERE (`users`.`name` = ? AND password=?) AND phone IN (?,?)",
+			vars: []interface{}{"tom", "123456", "1", "2"},
+			want: []interface{}{"tom", DefaultMask, DefaultMask, DefaultMask},
+		},
+		{
+			name: "insert",
+			sql:  "INSERT INTO `users` (`name`,`password`) VALUES (?,?),(?,?)",
+			vars: []interface{}{"tom", "123456", "amy", "654321"},
+			want: []interface{}{"tom", DefaultMask, "amy", DefaultMask},
+		},
+		{
+			name: "update",
+			sql:  "UPDATE `users` SET `password` = ?, `updated_at` = ? WHERE `id` = ?",
+			vars: []interface{}{"123456", "now", 1},
+			want: []interface{}{DefaultMask, "now", 1},
+		},
+		{
+			name: "quoted placeholder",
+			sql:  "SELECT * FROM users WHERE note = '?' AND password = ?",
+			vars: []interface{}{"123456"},
+			want: []interface{}{DefaultMask},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.SQLVars(tt.sql, tt.vars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SQLVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := MustNew(Rules{
+		Headers:   []string{"authorization"},
+		JSONPaths: []string{"**.password", "cards.*.number"},
+		RedisKeys: []string{"session:*"},
+		Patterns:  []string{PatternPhone},
+	})
+
+	header := r.Header(http.Header{"Authorization": {"Bearer abc"}, "X-Phone": {"13800138000"}})
+	if header.Get("Authorization") != DefaultMask || header.Get("X-Phone") != DefaultMask {
+		t.Errorf("Header() = %v", header)
+	}
+
+	body := r.JSON([]byte(`{"user":{"name":"tom","password":"123"},"cards":[{"number":"6222"}],"phone":"13800138000"}`))
+	want := `{"cards":[{"number":"******"}],"phone":"******","user":{"name":"tom","password":"******"}}`
+	if body != want {
+		t.Errorf("JSON() = %s, want %s", body, want)
+	}
+
+	if got := r.JSON([]byte("not json 13800138000")); got != "not json ******" {
+		t.Errorf("JSON() = %s", got)
+	}
+
+	args := r.RedisArgs([]interface{}{"set", "session:1", "token", "ex", 10})
+	if !reflect.DeepEqual(args, []interface{}{"set", "session:1", DefaultMask, DefaultMask, DefaultMask}) {
+		t.Errorf("RedisArgs() = %v", args)
+	}
+
+	args = r.RedisArgs([]interface{}{"mset", "user:1", "tom", "session:1", "token"})
+	if !reflect.DeepEqual(args, []interface{}{"mset", "user:1", "tom", "session:1", DefaultMask}) {
+		t.Errorf("RedisArgs() = %v", args)
+	}
+}
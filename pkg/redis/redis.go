@@ -2,13 +2,23 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v7"
 	"github.com/opentracing/opentracing-go"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 
+	"github.com/GaVender/era/pkg/backoff"
+	"github.com/GaVender/era/pkg/breaker"
+	"github.com/GaVender/era/pkg/errs"
 	"github.com/GaVender/era/pkg/log"
+	"github.com/GaVender/era/pkg/opentrace"
+	"github.com/GaVender/era/pkg/redact"
 )
 
 type (
@@ -31,16 +41,31 @@ type (
 		tracer          opentracing.Tracer
 		ableMonitor     bool
 		monitorInterval time.Duration
+		retry           backoff.Policy
+		slowThreshold   time.Duration
+		redactor        *redact.Redactor
+		breaker         *breaker.Breaker
+		reload          chan time.Duration
 		close           chan bool
 	}
 
+	// Error is returned with Kind ErrConnect or ErrClose.
+	Error = errs.Error
+
 	hook struct {
-		tracer      opentracing.Tracer
-		logger      log.Logger
-		beginTime   time.Time
-		ableMonitor bool
+		tracer        opentracing.Tracer
+		logger        log.Logger
+		db            int
+		ableMonitor   bool
+		slowThreshold time.Duration
+		redactor      *redact.Redactor
+		breaker       *breaker.Breaker
+		tags          opentracing.Tags
 	}
 
+	beginKey   struct{}
+	breakerKey struct{}
+
 	Option func(*Redis)
 )
 
@@ -49,7 +74,26 @@ const (
 	operationProcPipe = "redis: pipeline: "
 )
 
+var (
+	ErrConnect = errors.New("redis connect")
+	ErrClose   = errors.New("redis close")
+)
+
+// NewClient panics when the client can't connect, its closer logs a close error.
 func NewClient(cfg Config, opts ...Option) (Redis, func()) {
+	r, closer, err := NewClientE(cfg, opts...)
+	if err != nil {
+		panic("redis init: " + err.Error())
+	}
+
+	return r, func() {
+		if err := closer(); err != nil {
+			r.logger.Errorf(err.Error())
+		}
+	}
+}
+
+func NewClientE(cfg Config, opts ...Option) (Redis, func() error, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:         cfg.Addr,
 		Password:     cfg.Password,
@@ -62,12 +106,10 @@ func NewClient(cfg Config, opts ...Option) (Redis, func()) {
 		MinIdleConns: cfg.MinIdleConns,
 		IdleTimeout:  time.Millisecond * time.Duration(cfg.IdleTimeout),
 	})
-	if err := client.Ping().Err(); err != nil {
-		panic("redis init: " + err.Error())
-	}
 
 	r := Redis{
 		Client: client,
+		reload: make(chan time.Duration, 1),
 		close:  make(chan bool),
 	}
 	for _, opt := range opts {
@@ -78,18 +120,38 @@ func NewClient(cfg Config, opts ...Option) (Redis, func()) {
 		r.logger = log.NullLogger{}
 	}
 
+	err := backoff.Retry(context.Background(), r.retry, func(int) error {
+		return client.Ping().Err()
+	}, func(attempt int, err error, wait time.Duration) {
+		r.logger.Errorf("redis connect attempt %d: %s, retry in %s", attempt, err.Error(), wait)
+	})
+	if err != nil {
+		_ = client.Close()
+		return Redis{}, nil, &Error{Kind: ErrConnect, Err: err}
+	}
+
 	r.Client.AddHook(&hook{
-		tracer:      r.tracer,
-		logger:      r.logger,
-		ableMonitor: r.ableMonitor,
+		tracer:        r.tracer,
+		logger:        r.logger,
+		db:            cfg.DB,
+		ableMonitor:   r.ableMonitor,
+		slowThreshold: r.slowThreshold,
+		redactor:      r.redactor,
+		breaker:       r.breaker,
+		tags:          opentrace.DBTags("redis", strconv.Itoa(cfg.DB), cfg.Addr),
 	})
 
-	return r, func() {
-		if err := client.Close(); err != nil {
+	var once sync.Once
+	return r, func() error {
+		once.Do(func() {
 			close(r.close)
-			r.logger.Errorf("redis close: %s", err.Error())
+		})
+
+		if err := client.Close(); err != nil {
+			return &Error{Kind: ErrClose, Err: err}
 		}
-	}
+		return nil
+	}, nil
 }
 
 func WithLogger(logger log.Logger) Option {
@@ -104,6 +166,34 @@ func WithTracer(tracer opentracing.Tracer) Option {
 	}
 }
 
+// WithRetry retries the initial ping, by default the client fails on the first error.
+func WithRetry(policy backoff.Policy) Option {
+	return func(r *Redis) {
+		r.retry = policy
+	}
+}
+
+// WithSlowThreshold only logs the commands slower than threshold, failed commands are always logged.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(r *Redis) {
+		r.slowThreshold = threshold
+	}
+}
+
+// WithRedactor masks the logged and traced commands with redactor instead of redact.Default().
+func WithRedactor(redactor *redact.Redactor) Option {
+	return func(r *Redis) {
+		r.redactor = redactor
+	}
+}
+
+// WithBreaker fails the commands fast with breaker.ErrCircuitOpen while b is open, a missing key is no failure.
+func WithBreaker(b *breaker.Breaker) Option {
+	return func(r *Redis) {
+		r.breaker = b
+	}
+}
+
 func WithMonitor(able bool, interval time.Duration) Option {
 	return func(r *Redis) {
 		r.ableMonitor = able
@@ -111,23 +201,46 @@ func WithMonitor(able bool, interval time.Duration) Option {
 	}
 }
 
+// SetMonitorInterval changes the PerformanceStats interval without restarting it.
+func (r Redis) SetMonitorInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	select {
+	case <-r.reload:
+	default:
+	}
+
+	select {
+	case r.reload <- interval:
+	default:
+	}
+}
+
 func (r Redis) PerformanceStats() {
 	if !r.ableMonitor {
 		return
 	}
 
 	r.logger.Infof("redis performance statistics begin...")
+	r.performanceStats(r.monitorInterval)
+}
 
+// performanceStats restarts after a panic with the interval in use, the reloaded one if any.
+func (r Redis) performanceStats(interval time.Duration) {
 	go func() {
 		defer func() {
 			if err := recover(); err != nil {
 				r.logger.Errorf("redis performance stats: %v", err)
-				r.PerformanceStats()
+				r.performanceStats(interval)
 			}
 		}()
 
-		ticker := time.NewTicker(r.monitorInterval)
-		defer ticker.Stop()
+		ticker := time.NewTicker(interval)
+		defer func() {
+			ticker.Stop()
+		}()
 
 		for {
 			select {
@@ -140,6 +253,9 @@ func (r Redis) PerformanceStats() {
 				metricsRedisStatsGauge.WithLabelValues("hits").Set(float64(stats.Hits))
 				metricsRedisStatsGauge.WithLabelValues("misses").Set(float64(stats.Misses))
 				metricsRedisStatsGauge.WithLabelValues("timeout").Set(float64(stats.Timeouts))
+			case interval = <-r.reload:
+				ticker.Stop()
+				ticker = time.NewTicker(interval)
 			case <-r.close:
 				r.logger.Infof("redis stats stop...")
 				return
@@ -149,79 +265,151 @@ func (r Redis) PerformanceStats() {
 }
 
 func (h *hook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
-	h.beginTime = time.Now()
-	return ctx, nil
+	return h.before(ctx)
 }
 
 func (h *hook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
-	duration := time.Now().Sub(h.beginTime).Milliseconds()
+	h.done(ctx, h.err(cmd))
+	beginTime := h.beginTime(ctx)
+	duration := time.Now().Sub(beginTime)
 	operationInfo := operationProc + " " + cmd.Name()
 
 	if h.tracer != nil {
-		var childSp opentracing.Span
-		sp := opentracing.SpanFromContext(ctx)
-		if sp == nil {
-			childSp, ctx = opentracing.StartSpanFromContextWithTracer(
-				ctx,
-				h.tracer,
-				operationInfo,
-				opentracing.StartTime(h.beginTime),
-			)
-		} else {
-			childSp = h.tracer.StartSpan(
-				operationInfo,
-				opentracing.ChildOf(sp.Context()),
-				opentracing.StartTime(h.beginTime),
-			)
-		}
-
-		childSp.SetTag("command", cmd.String()).SetTag("error", cmd.Err())
-		childSp.Finish()
+		var sp opentracing.Span
+		sp, ctx = opentrace.StartChild(ctx, h.tracer, operationInfo, opentracing.StartTime(beginTime), h.tags)
+		h.tag(sp, cmd)
+		opentrace.Finish(sp, h.err(cmd))
 	}
 
 	if h.ableMonitor {
 		metricsRedisCmdCounter.WithLabelValues(cmd.Name()).Inc()
-		metricsRedisDurationHistogram.WithLabelValues(cmd.Name()).Observe(float64(duration))
+		metricsRedisDurationHistogram.WithLabelValues(cmd.Name()).Observe(float64(duration.Milliseconds()))
 	}
 
-	h.logger.ContextInfof(ctx, fmt.Sprint(operationProc, "cmd: ", cmd.String(), " , duration: ", duration))
+	h.log(ctx, operationProc+"process", cmd, duration)
 	return nil
 }
 
 func (h *hook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
-	h.beginTime = time.Now()
-	return ctx, nil
+	return h.before(ctx)
 }
 
 func (h *hook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if err = h.err(cmd); err != nil {
+			break
+		}
+	}
+	h.done(ctx, err)
+
+	beginTime := h.beginTime(ctx)
 	for _, cmd := range cmds {
-		duration := time.Now().Sub(h.beginTime).Milliseconds()
+		duration := time.Now().Sub(beginTime)
 		operationInfo := operationProcPipe + " " + cmd.Name()
 
+		cmdCtx := ctx
 		if h.tracer != nil {
-			var childSp opentracing.Span
-			sp := opentracing.SpanFromContext(ctx)
-			if sp == nil {
-				childSp, ctx = opentracing.StartSpanFromContext(ctx, operationInfo)
-			} else {
-				childSp = h.tracer.StartSpan(
-					operationInfo,
-					opentracing.ChildOf(sp.Context()),
-					opentracing.StartTime(h.beginTime),
-				)
-			}
-
-			childSp.SetTag("command", cmd.String()).SetTag("error", cmd.Err())
-			childSp.Finish()
+			var sp opentracing.Span
+			sp, cmdCtx = opentrace.StartChild(ctx, h.tracer, operationInfo, opentracing.StartTime(beginTime), h.tags)
+			h.tag(sp, cmd)
+			opentrace.Finish(sp, h.err(cmd))
 		}
 
 		if h.ableMonitor {
 			metricsRedisCmdCounter.WithLabelValues(cmd.Name()).Inc()
-			metricsRedisDurationHistogram.WithLabelValues(cmd.Name()).Observe(float64(duration))
+			metricsRedisDurationHistogram.WithLabelValues(cmd.Name()).Observe(float64(duration.Milliseconds()))
 		}
 
-		h.logger.ContextInfof(ctx, fmt.Sprint(operationInfo, " , duration: ", duration))
+		h.log(cmdCtx, operationProcPipe+"process", cmd, duration)
+	}
+
+	return nil
+}
+
+func (h *hook) before(ctx context.Context) (context.Context, error) {
+	if h.breaker != nil {
+		done, err := h.breaker.Allow()
+		if err != nil {
+			return ctx, err
+		}
+		ctx = context.WithValue(ctx, breakerKey{}, done)
+	}
+
+	return context.WithValue(ctx, beginKey{}, time.Now()), nil
+}
+
+func (h *hook) done(ctx context.Context, err error) {
+	if done, ok := ctx.Value(breakerKey{}).(func(error)); ok {
+		done(err)
+	}
+}
+
+func (h *hook) beginTime(ctx context.Context) time.Time {
+	if t, ok := ctx.Value(beginKey{}).(time.Time); ok {
+		return t
+	}
+
+	return time.Now()
+}
+
+func (h *hook) tag(sp opentracing.Span, cmd redis.Cmder) {
+	command := h.command(cmd)
+	sp.SetTag("command", command)
+	opentrace.SetAttributes(sp,
+		semconv.DBRedisDBIndex(h.db),
+		semconv.DBOperation(cmd.Name()),
+		semconv.DBStatement(command),
+	)
+}
+
+// err is the error of cmd, a missing key is not one.
+func (h *hook) err(cmd redis.Cmder) error {
+	if err := cmd.Err(); err != nil && err != redis.Nil {
+		return err
 	}
 
 	return nil
 }
+
+// command is cmd.String() redacted, the reply is left out when an argument had to be masked
+// or a key is masked, e.g. the value read by GET session:abc.
+func (h *hook) command(cmd redis.Cmder) string {
+	r := redact.Or(h.redactor)
+	args := r.RedisArgs(cmd.Args())
+
+	for i, arg := range args {
+		if arg == r.Mask() || i > 0 && r.RedisKey(fmt.Sprint(cmd.Args()[i])) {
+			ss := make([]string, len(args))
+			for i, a := range args {
+				ss[i] = fmt.Sprint(a)
+			}
+			return strings.Join(ss, " ")
+		}
+	}
+
+	return r.String(cmd.String())
+}
+
+func (h *hook) log(ctx context.Context, msg string, cmd redis.Cmder, duration time.Duration) {
+	err := h.err(cmd)
+	if err == nil && duration < h.slowThreshold {
+		log.CountDropped("redis", log.ReasonThreshold)
+		return
+	}
+
+	fields := []log.Field{
+		log.Int("db", h.db),
+		log.String("command", cmd.Name()),
+		log.String("args", fmt.Sprint(redact.Or(h.redactor).RedisArgs(cmd.Args())...)),
+		log.Duration("duration", duration),
+	}
+
+	logger := log.FromContextOr(ctx, h.logger).Named("redis")
+	if err != nil {
+		logger.ContextErrorField(ctx, msg, append(fields, log.Err(err))...)
+		return
+	}
+
+	logger.ContextInfoField(ctx, msg, fields...)
+}
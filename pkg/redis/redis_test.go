@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/GaVender/era/pkg/redact"
+)
+
+// serve answers the commands read on l with replies carrying "s3cr3t".
+func serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+				args := make([]string, n)
+				for i := range args {
+					if _, err := r.ReadString('\n'); err != nil {
+						return
+					}
+					arg, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					args[i] = strings.TrimSpace(arg)
+				}
+
+				reply := "+OK\r\n"
+				switch strings.ToLower(args[0]) {
+				case "get":
+					reply = "$6\r\ns3cr3t\r\n"
+				case "hgetall":
+					reply = "*2\r\n$5\r\ntoken\r\n$6\r\ns3cr3t\r\n"
+				}
+				if _, err := conn.Write([]byte(reply)); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func TestCommandTagRedactsMaskedKeys(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serve(l)
+
+	tracer := mocktracer.New()
+	r, closer, err := NewClientE(Config{Addr: l.Addr().String()},
+		WithTracer(tracer), WithRedactor(redact.MustNew(redact.Rules{RedisKeys: []string{"session:*"}})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer()
+
+	ctx := opentracing.ContextWithSpan(context.Background(), tracer.StartSpan("test"))
+	if v := r.WithContext(ctx).Get("session:abc").Val(); v != "s3cr3t" {
+		t.Fatalf("GET = %q", v)
+	}
+	r.WithContext(ctx).HGetAll("session:abc")
+	r.WithContext(ctx).Get("page:home")
+
+	want := []string{"get session:abc", "hgetall session:abc", "get page:home: s3cr3t"}
+	spans := tracer.FinishedSpans()
+	if len(spans) != len(want) {
+		t.Fatalf("%d spans", len(spans))
+	}
+	for i, sp := range spans {
+		if got := sp.Tag("command"); got != want[i] {
+			t.Errorf("command tag = %v, want %q", got, want[i])
+		}
+	}
+}